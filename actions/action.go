@@ -1,16 +1,23 @@
 package actions
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
 
+	gh "github.com/google/go-github/v48/github"
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
+	"github.com/ipfs/kuboreleaser/util"
 )
 
 type IAction interface {
-	Run() error
-	Check() error
+	Run(ctx context.Context) error
+	Check(ctx context.Context) error
 }
 
 var (
@@ -19,33 +26,145 @@ var (
 	ErrFailure    = errors.New("the action failed and requires manual intervention")
 )
 
-func CheckBranch(github *github.Client, owner, repo, branch string) error {
-	runs, err := github.GetIncompleteCheckRuns(owner, repo, branch)
+func Status(err error) journal.Status {
+	switch {
+	case err == nil:
+		return journal.StatusDone
+	case errors.Is(err, ErrInProgress):
+		return journal.StatusInProgress
+	case errors.Is(err, ErrIncomplete):
+		return journal.StatusIncomplete
+	default:
+		return journal.StatusFailed
+	}
+}
+
+// ErrorClass returns the name of the sentinel error that err wraps, if any
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrInProgress):
+		return "ErrInProgress"
+	case errors.Is(err, ErrIncomplete):
+		return "ErrIncomplete"
+	case errors.Is(err, ErrFailure):
+		return "ErrFailure"
+	case errors.Is(err, github.ErrUnauthorized):
+		return "ErrUnauthorized"
+	case errors.Is(err, github.ErrRateLimited):
+		return "ErrRateLimited"
+	case errors.Is(err, github.ErrNotFound):
+		return "ErrNotFound"
+	case errors.Is(err, github.ErrConflict):
+		return "ErrConflict"
+	default:
+		return "error"
+	}
+}
+
+// branchCheck is a check run or a commit status reported on a branch
+type branchCheck struct {
+	name    string
+	url     string
+	pending bool
+	ok      bool
+}
+
+// CheckBranch gates on the checks that the base branch requires, whether they report through check runs or legacy commit statuses.
+// The failures of the optional checks are only warnings. If the base branch does not require any checks, all of them are required.
+func CheckBranch(ctx context.Context, github github.API, owner, repo, branch, base string) error {
+	// the check runs are listed once and filtered here to save on the API quota
+	runs, err := github.GetCheckRuns(ctx, owner, repo, branch)
 	if err != nil {
 		return err
 	}
-	if len(runs) > 0 {
-		return fmt.Errorf("⚠️ check %s on https://github.com/%s/%s/tree/%s is not completed yet (%w)", runs[0].GetName(), owner, repo, branch, ErrInProgress)
+	statuses, err := github.GetCommitStatuses(ctx, owner, repo, branch)
+	if err != nil {
+		return err
 	}
-
-	runs, err = github.GetUnsuccessfulCheckRuns(owner, repo, branch)
+	required, err := github.GetRequiredStatusChecks(ctx, owner, repo, base)
 	if err != nil {
 		return err
 	}
-	if len(runs) > 0 {
-		return fmt.Errorf("⚠️ check %s on https://github.com/%s/%s/tree/%s is not successful (%w)", runs[0].GetName(), owner, repo, branch, ErrIncomplete)
+
+	var checks []branchCheck
+	for _, r := range runs {
+		conclusion := r.GetConclusion()
+		checks = append(checks, branchCheck{
+			name:    r.GetName(),
+			url:     r.GetHTMLURL(),
+			pending: r.GetStatus() != "completed",
+			ok:      conclusion == "success" || conclusion == "skipped" || conclusion == "neutral",
+		})
+	}
+	for _, s := range statuses {
+		checks = append(checks, branchCheck{
+			name:    s.GetContext(),
+			url:     s.GetTargetURL(),
+			pending: s.GetState() == "pending",
+			ok:      s.GetState() == "success",
+		})
+	}
+
+	isRequired := make(map[string]bool)
+	for _, name := range required {
+		isRequired[name] = true
+	}
+	reported := make(map[string]bool)
+	for _, c := range checks {
+		reported[c.name] = true
+		if len(required) == 0 {
+			isRequired[c.name] = true
+		}
+	}
+
+	for _, c := range checks {
+		if !isRequired[c.name] && !c.pending && !c.ok {
+			util.Logger(ctx).WithField("url", c.url).Warn("⚠️ Optional check ", c.name, " on ", util.GitHubServerURL(), "/", owner, "/", repo, "/tree/", branch, " is not successful")
+		}
+	}
+	for _, name := range required {
+		if !reported[name] {
+			return fmt.Errorf("⚠️ required check %s has not reported on %s/%s/%s/tree/%s yet (%w)", name, util.GitHubServerURL(), owner, repo, branch, ErrInProgress)
+		}
+	}
+	for _, c := range checks {
+		if isRequired[c.name] && c.pending {
+			return fmt.Errorf("⚠️ check %s on %s/%s/%s/tree/%s is not completed yet (%w)", c.name, util.GitHubServerURL(), owner, repo, branch, ErrInProgress)
+		}
+	}
+	for _, c := range checks {
+		if isRequired[c.name] && !c.ok {
+			return fmt.Errorf("⚠️ check %s on %s/%s/%s/tree/%s is not successful (%w)", c.name, util.GitHubServerURL(), owner, repo, branch, ErrIncomplete)
+		}
 	}
 
 	return nil
 }
 
-func CheckPR(github *github.Client, owner, repo, head string, shouldBeMerged bool) error {
-	pr, err := github.GetPR(owner, repo, head)
+// enableAutoMerge asks GitHub to merge the PR with the method once its checks pass, and reports whether it will.
+// A failure, e.g. because auto-merge is not allowed in the repository, is only a warning since the PR can still be merged by hand.
+func enableAutoMerge(ctx context.Context, github github.API, pr *gh.PullRequest, method github.MergeMethod) bool {
+	if pr.GetMerged() || pr.GetState() == "closed" || pr.GetDraft() {
+		return false
+	}
+	err := github.EnableAutoMerge(ctx, pr, method)
+	if err != nil {
+		util.Logger(ctx).WithField("url", pr.GetHTMLURL()).Warn("⚠️ Failed to enable auto-merge, the PR has to be merged manually: ", err)
+		return false
+	}
+	util.Logger(ctx).WithField("url", pr.GetHTMLURL()).Info("🤖 Auto-merge (", strings.ToLower(string(method)), ") is enabled, GitHub will merge the PR once its checks pass")
+	return true
+}
+
+func CheckPR(ctx context.Context, github github.API, owner, repo, head string, shouldBeMerged bool) error {
+	pr, err := github.GetPR(ctx, owner, repo, head)
 	if err != nil {
 		return err
 	}
 	if pr == nil {
-		return fmt.Errorf("⚠️ PR for https://github.com/%s/%s/tree/%s not found (%w)", owner, repo, head, ErrIncomplete)
+		return fmt.Errorf("⚠️ PR for %s/%s/%s/tree/%s not found (%w)", util.GitHubServerURL(), owner, repo, head, ErrIncomplete)
 	}
 
 	if !pr.GetMerged() {
@@ -53,7 +172,7 @@ func CheckPR(github *github.Client, owner, repo, head string, shouldBeMerged boo
 			return fmt.Errorf("⚠️ %s is closed (%w)", pr.GetHTMLURL(), ErrIncomplete)
 		}
 
-		err = CheckBranch(github, owner, repo, head)
+		err = CheckBranch(ctx, github, owner, repo, head, pr.GetBase().GetRef())
 		if err != nil {
 			return err
 		}
@@ -66,13 +185,50 @@ func CheckPR(github *github.Client, owner, repo, head string, shouldBeMerged boo
 	return nil
 }
 
-func CheckWorkflowRun(github *github.Client, owner, repo, branch, file, job, pattern string) error {
-	run, err := github.GetWorkflowRun(owner, repo, branch, file, false)
+// dispatchedWorkflowRun returns the handle of the last run of the workflow that the action dispatched, or nil if the journal does not know about one
+func dispatchedWorkflowRun(entry *journal.Entry, owner, repo, file string) *github.WorkflowRunHandle {
+	var handle *github.WorkflowRunHandle
+	for _, a := range entry.GetArtifacts() {
+		if a.Kind == journal.ArtifactWorkflowRun && a.Owner == owner && a.Repo == repo && a.Name == file {
+			handle = github.NewWorkflowRunHandle(a)
+		}
+	}
+	return handle
+}
+
+// findWorkflowRun returns the run of the workflow that the action dispatched.
+// If the journal does not know about one, e.g. because the workflow was dispatched by hand, the newest run on the branch is returned instead.
+func findWorkflowRun(ctx context.Context, client github.API, entry *journal.Entry, owner, repo, branch, file string) (*gh.WorkflowRun, error) {
+	if handle := dispatchedWorkflowRun(entry, owner, repo, file); handle != nil {
+		run, err := client.FindWorkflowRun(ctx, handle)
+		if err != nil {
+			return nil, err
+		}
+		if run == nil && handle.ID != 0 {
+			return nil, fmt.Errorf("⚠️ %s not found (%w)", handle.URL, ErrIncomplete)
+		}
+		if run == nil {
+			return nil, fmt.Errorf("⚠️ the run of %s dispatched on %s at %s has not started yet, see %s (%w)", file, handle.Ref, handle.DispatchedAt.Format(time.DateTime), handle.URL, ErrInProgress)
+		}
+		return run, nil
+	}
+
+	run, err := client.GetWorkflowRun(ctx, owner, repo, branch, file, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if run == nil {
-		return fmt.Errorf("⚠️ workflow run %s for https://github.com/%s/%s/tree/%s not found (%w)", file, owner, repo, branch, ErrIncomplete)
+		return nil, fmt.Errorf("⚠️ workflow run %s for %s/%s/%s/tree/%s not found (%w)", file, util.GitHubServerURL(), owner, repo, branch, ErrIncomplete)
+	}
+	return run, nil
+}
+
+// CheckWorkflowRun verifies the run of the workflow that the action dispatched, see findWorkflowRun.
+// The pattern is looked for in the logs of the job, or only in the logs of the step if one is given.
+func CheckWorkflowRun(ctx context.Context, client github.API, entry *journal.Entry, owner, repo, branch, file, job, step, pattern string) error {
+	run, err := findWorkflowRun(ctx, client, entry, owner, repo, branch, file)
+	if err != nil {
+		return err
 	}
 
 	if run.GetStatus() != "completed" {
@@ -82,17 +238,28 @@ func CheckWorkflowRun(github *github.Client, owner, repo, branch, file, job, pat
 		return fmt.Errorf("⚠️ %s did not succeed (%w)", run.GetHTMLURL(), ErrFailure)
 	}
 
-	runLogs, err := github.GetWorkflowRunLogs(owner, repo, run.GetID())
+	runLogs, err := client.GetWorkflowRunLogs(ctx, owner, repo, run.GetID(), run.GetRunAttempt())
 	if err != nil {
 		return err
 	}
 
+	defer runLogs.Close()
+
 	jobLogs := runLogs.JobLogs[job]
 	if jobLogs == nil {
 		return fmt.Errorf("⚠️ %s does not have a %s job (%w)", run.GetHTMLURL(), job, ErrFailure)
 	}
+	logs := jobLogs.Open()
+	if step != "" {
+		stepLogs := jobLogs.JobStepLogs[step]
+		if stepLogs == nil {
+			return fmt.Errorf("⚠️ %s does not have a %s step in the %s job (%w)", run.GetHTMLURL(), step, job, ErrFailure)
+		}
+		logs = stepLogs.Open()
+	}
+	defer logs.Close()
 
-	matched, err := regexp.MatchString(pattern, jobLogs.RawLogs)
+	matched, err := regexp.MatchReader(pattern, bufio.NewReader(logs))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,464 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v48/github"
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/github/githubtest"
+	"github.com/ipfs/kuboreleaser/journal"
+	"github.com/ipfs/kuboreleaser/util"
+)
+
+const (
+	owner = "ipfs"
+	repo  = "kubo"
+)
+
+func TestCheckBranch(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *githubtest.Fake)
+		want  string
+	}{{
+		name:  "no checks",
+		setup: func(f *githubtest.Fake) {},
+		want:  "",
+	}, {
+		name: "check run queued",
+		setup: func(f *githubtest.Fake) {
+			f.AddCheckRun(owner, repo, "release-v0.30.0", "build", "success")
+			f.SetCheckRunStatus(owner, repo, "release-v0.30.0", "build", "queued")
+		},
+		want: "ErrInProgress",
+	}, {
+		name: "check run in progress",
+		setup: func(f *githubtest.Fake) {
+			f.AddCheckRun(owner, repo, "release-v0.30.0", "build", "success")
+			f.SetCheckRunStatus(owner, repo, "release-v0.30.0", "build", "in_progress")
+		},
+		want: "ErrInProgress",
+	}, {
+		name: "check run succeeded",
+		setup: func(f *githubtest.Fake) {
+			f.AddCheckRun(owner, repo, "release-v0.30.0", "build", "success")
+			f.SetCheckRunStatus(owner, repo, "release-v0.30.0", "build", "completed")
+		},
+		want: "",
+	}, {
+		name: "check run failed without required checks",
+		setup: func(f *githubtest.Fake) {
+			f.AddCheckRun(owner, repo, "release-v0.30.0", "build", "failure")
+			f.SetCheckRunStatus(owner, repo, "release-v0.30.0", "build", "completed")
+		},
+		want: "ErrIncomplete",
+	}, {
+		name: "required check not reported",
+		setup: func(f *githubtest.Fake) {
+			f.SetRequiredStatusChecks(owner, repo, "release", "build")
+			f.SetCommitStatus(owner, repo, "release-v0.30.0", "lint", "success")
+		},
+		want: "ErrInProgress",
+	}, {
+		name: "required status pending",
+		setup: func(f *githubtest.Fake) {
+			f.SetRequiredStatusChecks(owner, repo, "release", "build")
+			f.SetCommitStatus(owner, repo, "release-v0.30.0", "build", "pending")
+		},
+		want: "ErrInProgress",
+	}, {
+		name: "required status failed",
+		setup: func(f *githubtest.Fake) {
+			f.SetRequiredStatusChecks(owner, repo, "release", "build")
+			f.SetCommitStatus(owner, repo, "release-v0.30.0", "build", "failure")
+		},
+		want: "ErrIncomplete",
+	}, {
+		name: "optional check failed",
+		setup: func(f *githubtest.Fake) {
+			f.SetRequiredStatusChecks(owner, repo, "release", "build")
+			f.SetCommitStatus(owner, repo, "release-v0.30.0", "build", "success")
+			f.AddCheckRun(owner, repo, "release-v0.30.0", "flaky", "failure")
+			f.SetCheckRunStatus(owner, repo, "release-v0.30.0", "flaky", "completed")
+		},
+		want: "",
+	}, {
+		name: "optional check pending",
+		setup: func(f *githubtest.Fake) {
+			f.SetRequiredStatusChecks(owner, repo, "release", "build")
+			f.AddCheckRun(owner, repo, "release-v0.30.0", "build", "success")
+			f.SetCheckRunStatus(owner, repo, "release-v0.30.0", "build", "completed")
+			f.SetCommitStatus(owner, repo, "release-v0.30.0", "slow", "pending")
+		},
+		want: "",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := githubtest.NewFake()
+			tt.setup(f)
+
+			err := CheckBranch(context.Background(), f, owner, repo, "release-v0.30.0", "release")
+			if got := ErrorClass(err); got != tt.want {
+				t.Errorf("CheckBranch() = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPR(t *testing.T) {
+	tests := []struct {
+		name           string
+		setup          func(t *testing.T, f *githubtest.Fake)
+		shouldBeMerged bool
+		want           string
+	}{{
+		name:  "not found",
+		setup: func(t *testing.T, f *githubtest.Fake) {},
+		want:  "ErrIncomplete",
+	}, {
+		name: "closed",
+		setup: func(t *testing.T, f *githubtest.Fake) {
+			pr := createPR(t, f)
+			if err := f.ClosePR(context.Background(), pr); err != nil {
+				t.Fatal(err)
+			}
+		},
+		want: "ErrIncomplete",
+	}, {
+		name: "open",
+		setup: func(t *testing.T, f *githubtest.Fake) {
+			createPR(t, f)
+		},
+		want: "",
+	}, {
+		name: "open with a failed required check",
+		setup: func(t *testing.T, f *githubtest.Fake) {
+			createPR(t, f)
+			f.SetRequiredStatusChecks(owner, repo, "release", "build")
+			f.SetCommitStatus(owner, repo, "release-v0.30.0", "build", "failure")
+		},
+		want: "ErrIncomplete",
+	}, {
+		name: "open but should be merged",
+		setup: func(t *testing.T, f *githubtest.Fake) {
+			createPR(t, f)
+		},
+		shouldBeMerged: true,
+		want:           "ErrInProgress",
+	}, {
+		name: "merged",
+		setup: func(t *testing.T, f *githubtest.Fake) {
+			pr := createPR(t, f)
+			if err := f.MergePR(owner, repo, pr.GetNumber()); err != nil {
+				t.Fatal(err)
+			}
+		},
+		shouldBeMerged: true,
+		want:           "",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := githubtest.NewFake()
+			tt.setup(t, f)
+
+			err := CheckPR(context.Background(), f, owner, repo, "release-v0.30.0", tt.shouldBeMerged)
+			if got := ErrorClass(err); got != tt.want {
+				t.Errorf("CheckPR() = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func createPR(t *testing.T, f *githubtest.Fake) *gh.PullRequest {
+	f.SetBranch(owner, repo, "release")
+	f.SetBranch(owner, repo, "release-v0.30.0")
+	pr, err := f.CreatePR(context.Background(), owner, repo, "release-v0.30.0", "release", "Release v0.30.0", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pr
+}
+
+func TestCheckWorkflowRun(t *testing.T) {
+	logs := map[string]string{
+		"0_publish.txt":                "2024-01-01T00:00:00.0000000Z Publishing...\n2024-01-01T00:00:01.0000000Z + kubo@0.30.0\n",
+		"publish/1_Set up job.txt":     "2024-01-01T00:00:00.0000000Z Publishing...\n",
+		"publish/2_Publish to npm.txt": "2024-01-01T00:00:01.0000000Z + kubo@0.30.0\n",
+	}
+
+	tests := []struct {
+		name       string
+		conclusion string
+		logs       map[string]string
+		// status is the status of the dispatched run, no run is dispatched without it
+		status  string
+		job     string
+		step    string
+		pattern string
+		want    string
+	}{{
+		name:    "not dispatched",
+		job:     "publish",
+		pattern: "kubo@0.30.0",
+		want:    "ErrIncomplete",
+	}, {
+		name:    "in progress",
+		status:  "in_progress",
+		job:     "publish",
+		pattern: "kubo@0.30.0",
+		want:    "ErrInProgress",
+	}, {
+		name:    "succeeded",
+		logs:    logs,
+		status:  "completed",
+		job:     "publish",
+		pattern: "kubo@0.30.0",
+		want:    "",
+	}, {
+		name:    "succeeded in the step",
+		logs:    logs,
+		status:  "completed",
+		job:     "publish",
+		step:    "Publish to npm",
+		pattern: "kubo@0.30.0",
+		want:    "",
+	}, {
+		name:    "pattern not in the step",
+		logs:    logs,
+		status:  "completed",
+		job:     "publish",
+		step:    "Set up job",
+		pattern: "kubo@0.30.0",
+		want:    "ErrIncomplete",
+	}, {
+		name:    "pattern not found",
+		logs:    logs,
+		status:  "completed",
+		job:     "publish",
+		pattern: "kubo@0.31.0",
+		want:    "ErrIncomplete",
+	}, {
+		name:    "job not found",
+		logs:    logs,
+		status:  "completed",
+		job:     "build",
+		pattern: "kubo@0.30.0",
+		want:    "ErrFailure",
+	}, {
+		name:    "step not found",
+		logs:    logs,
+		status:  "completed",
+		job:     "publish",
+		step:    "Build",
+		pattern: "kubo@0.30.0",
+		want:    "ErrFailure",
+	}, {
+		name:       "failed",
+		conclusion: "failure",
+		logs:       logs,
+		status:     "completed",
+		job:        "publish",
+		pattern:    "kubo@0.30.0",
+		want:       "ErrFailure",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := githubtest.NewFake()
+			if tt.conclusion != "" {
+				f.WorkflowConclusions["main.yml"] = tt.conclusion
+			}
+			if tt.logs != nil {
+				f.WorkflowLogs["main.yml"] = github.NewWorkflowRunLogs(tt.logs)
+			}
+			if tt.status != "" {
+				handle, err := f.CreateWorkflowRun(context.Background(), owner, repo, "main.yml", "master")
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.SetWorkflowRunStatus(owner, repo, handle.ID, tt.status)
+			}
+
+			err := CheckWorkflowRun(context.Background(), f, nil, owner, repo, "master", "main.yml", tt.job, tt.step, tt.pattern)
+			if got := ErrorClass(err); got != tt.want {
+				t.Errorf("CheckWorkflowRun() = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindWorkflowRun(t *testing.T) {
+	ctx := context.Background()
+
+	newEntry := func(t *testing.T) *journal.Entry {
+		j, err := journal.Open(t.TempDir(), "v0.30.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return j.Entry("publish-to-npm")
+	}
+	record := func(entry *journal.Entry, handle *github.WorkflowRunHandle) {
+		entry.Record(journal.Artifact{
+			Kind:  journal.ArtifactWorkflowRun,
+			Owner: handle.Owner,
+			Repo:  handle.Repo,
+			Name:  handle.File,
+			ID:    handle.ID,
+			Ref:   handle.Ref,
+			Actor: handle.Actor,
+			URL:   handle.URL,
+			Time:  handle.DispatchedAt,
+		})
+	}
+
+	t.Run("dispatched run rather than a newer one", func(t *testing.T) {
+		f := githubtest.NewFake()
+		entry := newEntry(t)
+		handle, err := f.CreateWorkflowRun(ctx, owner, repo, "main.yml", "master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		record(entry, handle)
+		// someone dispatches the workflow by hand afterwards
+		if _, err := f.CreateWorkflowRun(ctx, owner, repo, "main.yml", "master"); err != nil {
+			t.Fatal(err)
+		}
+
+		run, err := findWorkflowRun(ctx, f, entry, owner, repo, "master", "main.yml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.GetID() != handle.ID {
+			t.Errorf("findWorkflowRun() = %d, want %d", run.GetID(), handle.ID)
+		}
+	})
+
+	t.Run("dispatched run not started yet", func(t *testing.T) {
+		f := githubtest.NewFake()
+		entry := newEntry(t)
+		record(entry, &github.WorkflowRunHandle{
+			Owner:        owner,
+			Repo:         repo,
+			File:         "main.yml",
+			Ref:          "master",
+			Actor:        f.Login,
+			DispatchedAt: time.Now(),
+		})
+
+		_, err := findWorkflowRun(ctx, f, entry, owner, repo, "master", "main.yml")
+		if got := ErrorClass(err); got != "ErrInProgress" {
+			t.Errorf("findWorkflowRun() = %v, want ErrInProgress", err)
+		}
+	})
+
+	t.Run("dispatched run not found", func(t *testing.T) {
+		f := githubtest.NewFake()
+		entry := newEntry(t)
+		record(entry, &github.WorkflowRunHandle{
+			Owner:        owner,
+			Repo:         repo,
+			File:         "main.yml",
+			Ref:          "master",
+			Actor:        f.Login,
+			DispatchedAt: time.Now(),
+			ID:           42,
+		})
+
+		_, err := findWorkflowRun(ctx, f, entry, owner, repo, "master", "main.yml")
+		if got := ErrorClass(err); got != "ErrIncomplete" {
+			t.Errorf("findWorkflowRun() = %v, want ErrIncomplete", err)
+		}
+	})
+
+	t.Run("newest run without a journal", func(t *testing.T) {
+		f := githubtest.NewFake()
+		if _, err := f.CreateWorkflowRun(ctx, owner, repo, "main.yml", "master"); err != nil {
+			t.Fatal(err)
+		}
+		newest, err := f.CreateWorkflowRun(ctx, owner, repo, "main.yml", "master")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		run, err := findWorkflowRun(ctx, f, nil, owner, repo, "master", "main.yml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.GetID() != newest.ID {
+			t.Errorf("findWorkflowRun() = %d, want %d", run.GetID(), newest.ID)
+		}
+	})
+}
+
+func TestRevert(t *testing.T) {
+	ctx := context.Background()
+	f := githubtest.NewFake()
+	sha := f.SetBranch(owner, repo, "release-v0.30.0")
+	f.SetTag(owner, repo, "v0.30.0", "v0.30.0")
+	release, err := f.CreateRelease(ctx, owner, repo, "v0.30.0", "v0.30.0", "", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifacts := []journal.Artifact{
+		{Kind: journal.ArtifactBranch, Owner: owner, Repo: repo, Name: "release-v0.30.0", SHA: sha},
+		{Kind: journal.ArtifactTag, Owner: owner, Repo: repo, Name: "v0.30.0"},
+		{Kind: journal.ArtifactRelease, Owner: owner, Repo: repo, Name: "v0.30.0", ID: release.GetID()},
+	}
+
+	// the published release is refused, so its tag stays too, but the branch does not depend on either
+	err = revert(ctx, f, artifacts)
+	partial, ok := err.(*RevertError)
+	if !ok {
+		t.Fatalf("revert() = %v, want a RevertError", err)
+	}
+	if len(partial.Reverted) != 1 || partial.Reverted[0].Kind != journal.ArtifactBranch {
+		t.Errorf("reverted %v, want the branch", partial.Reverted)
+	}
+	if len(partial.Remaining) != 2 || partial.Remaining[0].Kind != journal.ArtifactRelease || partial.Remaining[1].Kind != journal.ArtifactTag {
+		t.Errorf("remaining %v, want the release and the tag", partial.Remaining)
+	}
+	if branch, _ := f.GetBranch(ctx, owner, repo, "release-v0.30.0"); branch != nil {
+		t.Error("the branch was not deleted")
+	}
+	if tag, _ := f.GetTag(ctx, owner, repo, "v0.30.0"); tag == nil {
+		t.Error("the tag was deleted")
+	}
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	f := githubtest.NewFake()
+	version, err := util.NewVersion("v0.30.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := AcquireLock(ctx, f, version, "alice@host#1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Release(ctx)
+
+	// the same maintainer on the same machine is held off by the nonce
+	if _, err := AcquireLock(ctx, f, version, "alice@host#2", false); err == nil {
+		t.Fatal("AcquireLock() succeeded while the lock was held")
+	}
+
+	second, err := AcquireLock(ctx, f, version, "alice@host#2", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Release(ctx)
+
+	if err := first.heartbeat(ctx, false); !errors.Is(err, errLockLost) {
+		t.Errorf("heartbeat() = %v, want errLockLost", err)
+	}
+	if err := second.heartbeat(ctx, false); err != nil {
+		t.Errorf("heartbeat() = %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	gh "github.com/google/go-github/v48/github"
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
+	"github.com/ipfs/kuboreleaser/repos"
+	"github.com/ipfs/kuboreleaser/util"
+)
+
+const (
+	artifactsStart = "<!-- kuboreleaser-artifacts-start -->"
+	artifactsEnd   = "<!-- kuboreleaser-artifacts-end -->"
+)
+
+// the actions which finish concurrently would otherwise overwrite each other's updates of the release issue
+var artifactsMu sync.Mutex
+
+var (
+	releaseIssuesMu sync.Mutex
+	// releaseIssues caches the numbers of the release issues by version, finding one by its title goes through the Search API which only allows 30 requests a minute
+	releaseIssues = map[string]int{}
+)
+
+// releaseIssueNumber returns the number of the release issue of the version, or 0 if there is no release issue yet
+func releaseIssueNumber(ctx context.Context, github github.API, version *util.Version) (int, error) {
+	releaseIssuesMu.Lock()
+	defer releaseIssuesMu.Unlock()
+	if number, ok := releaseIssues[version.String()]; ok {
+		return number, nil
+	}
+	issue, err := github.GetIssue(ctx, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseIssueTitle(version))
+	if err != nil || issue == nil {
+		// a missing release issue is not cached, it might be created later on
+		return 0, err
+	}
+	releaseIssues[version.String()] = issue.GetNumber()
+	return issue.GetNumber(), nil
+}
+
+func rememberReleaseIssue(version *util.Version, number int) {
+	if number == 0 {
+		return
+	}
+	releaseIssuesMu.Lock()
+	defer releaseIssuesMu.Unlock()
+	releaseIssues[version.String()] = number
+}
+
+// releaseIssueRef returns the line which links the PRs to the release issue, or an empty string if there is no release issue yet
+func releaseIssueRef(ctx context.Context, github github.API, version *util.Version) string {
+	number, err := releaseIssueNumber(ctx, github, version)
+	if err != nil {
+		util.Logger(ctx).Warn("⚠️ Failed to find the release issue to link the PR to: ", err)
+		return ""
+	}
+	if number == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Part of the release tracked in %s/%s#%d", repos.Kubo.Owner, repos.Kubo.Repo, number)
+}
+
+// withReleaseIssueRef appends the link to the release issue to the body of a PR
+func withReleaseIssueRef(ctx context.Context, github github.API, version *util.Version, body string) string {
+	ref := releaseIssueRef(ctx, github, version)
+	if ref == "" {
+		return body
+	}
+	return body + "\n\n" + ref
+}
+
+// SyncReleaseArtifacts maintains the "Release artifacts" section of the release issue.
+// It lists the PRs, tags, releases and workflow runs that the journal says the actions created, together with their current status.
+// The section is rewritten in place between its markers, so the issue never gets more than one.
+func SyncReleaseArtifacts(ctx context.Context, github github.API, version *util.Version, j *journal.Journal) error {
+	artifactsMu.Lock()
+	defer artifactsMu.Unlock()
+
+	var rows []string
+	for _, r := range Registry {
+		for _, a := range j.Lookup(r.Name).GetArtifacts() {
+			status, ok := artifactStatus(ctx, github, a)
+			if !ok {
+				continue
+			}
+			rows = append(rows, fmt.Sprintf("| %s | [%s](%s) | %s |", r.Name, artifactTitle(a), a.URL, status))
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	number, err := releaseIssueNumber(ctx, github, version)
+	if err != nil {
+		return err
+	}
+	var issue *gh.Issue
+	if number != 0 {
+		issue, err = github.GetIssueByNumber(ctx, repos.Kubo.Owner, repos.Kubo.Repo, number)
+		if err != nil {
+			return err
+		}
+	}
+	if issue == nil {
+		util.Logger(ctx).Debug("Not syncing the release artifacts because the release issue does not exist")
+		return nil
+	}
+
+	section := fmt.Sprintf(`%s
+## Release artifacts
+
+| Step | Artifact | Status |
+| --- | --- | --- |
+%s
+%s`, artifactsStart, strings.Join(rows, "\n"), artifactsEnd)
+
+	body := issue.GetBody()
+	start := strings.Index(body, artifactsStart)
+	end := strings.Index(body, artifactsEnd)
+	if start >= 0 && end > start {
+		body = body[:start] + section + body[end+len(artifactsEnd):]
+	} else {
+		body = strings.TrimRight(body, "\n") + "\n\n" + section
+	}
+	if body == issue.GetBody() {
+		util.Logger(ctx).Debug("The release artifacts are up to date")
+		return nil
+	}
+
+	_, err = github.UpdateIssue(ctx, repos.Kubo.Owner, repos.Kubo.Repo, issue.GetNumber(), body)
+	if err != nil {
+		return err
+	}
+	util.Logger(ctx).WithField("url", issue.GetHTMLURL()).Info("Updated the release artifacts in the release issue")
+	return nil
+}
+
+func artifactTitle(a journal.Artifact) string {
+	switch a.Kind {
+	case journal.ArtifactPR:
+		return fmt.Sprintf("%s/%s#%d", a.Owner, a.Repo, a.ID)
+	case journal.ArtifactWorkflowRun:
+		if a.ID != 0 {
+			return fmt.Sprintf("%s/%s %s run %d", a.Owner, a.Repo, a.Name, a.ID)
+		}
+		return fmt.Sprintf("%s/%s %s", a.Owner, a.Repo, a.Name)
+	default:
+		return fmt.Sprintf("%s/%s %s %s", a.Owner, a.Repo, a.Kind, a.Name)
+	}
+}
+
+// artifactStatus looks up the current status of the artifact, it reports false for the kinds which are not listed
+func artifactStatus(ctx context.Context, github github.API, a journal.Artifact) (string, bool) {
+	var status string
+	var err error
+	switch a.Kind {
+	case journal.ArtifactPR:
+		status, err = prStatus(ctx, github, a)
+	case journal.ArtifactTag:
+		status, err = tagStatus(ctx, github, a)
+	case journal.ArtifactRelease:
+		status, err = releaseStatus(ctx, github, a)
+	case journal.ArtifactWorkflowRun:
+		status, err = workflowRunStatus(ctx, github, a)
+	default:
+		return "", false
+	}
+	if err != nil {
+		util.Logger(ctx).WithField("url", a.URL).Warn("⚠️ Failed to get the status of the ", a.Kind, ": ", err)
+		return "❔ unknown", true
+	}
+	return status, true
+}
+
+func prStatus(ctx context.Context, github github.API, a journal.Artifact) (string, error) {
+	pr, err := github.GetPRByNumber(ctx, a.Owner, a.Repo, int(a.ID))
+	switch {
+	case err != nil:
+		return "", err
+	case pr == nil:
+		return "🗑️ deleted", nil
+	case pr.GetMerged():
+		return "🟣 merged", nil
+	case pr.GetState() == "closed":
+		return "🔴 closed", nil
+	case pr.GetDraft():
+		return "📝 draft", nil
+	case pr.AutoMerge != nil:
+		return "🟢 open, auto-merge enabled", nil
+	default:
+		return "🟢 open", nil
+	}
+}
+
+func tagStatus(ctx context.Context, github github.API, a journal.Artifact) (string, error) {
+	tag, err := github.GetTag(ctx, a.Owner, a.Repo, a.Name)
+	switch {
+	case err != nil:
+		return "", err
+	case tag == nil:
+		return "🗑️ deleted", nil
+	default:
+		return "✅ pushed", nil
+	}
+}
+
+func releaseStatus(ctx context.Context, github github.API, a journal.Artifact) (string, error) {
+	release, err := github.GetRelease(ctx, a.Owner, a.Repo, a.Name)
+	switch {
+	case err != nil:
+		return "", err
+	case release == nil:
+		return "🗑️ deleted", nil
+	case release.GetDraft():
+		return "📝 draft", nil
+	case release.GetPrerelease():
+		return "✅ published as a prerelease", nil
+	default:
+		return "✅ published", nil
+	}
+}
+
+func workflowRunStatus(ctx context.Context, github github.API, a journal.Artifact) (string, error) {
+	// the runs are only identified once they are found after being triggered
+	if a.ID == 0 {
+		return "🚀 triggered", nil
+	}
+	run, err := github.GetWorkflowRunByID(ctx, a.Owner, a.Repo, a.ID)
+	switch {
+	case err != nil:
+		return "", err
+	case run == nil:
+		return "🗑️ deleted", nil
+	case run.GetStatus() != "completed":
+		return "⏳ " + strings.ReplaceAll(run.GetStatus(), "_", " "), nil
+	case run.GetConclusion() == "success":
+		return "✅ succeeded", nil
+	default:
+		return "❌ " + strings.ReplaceAll(run.GetConclusion(), "_", " "), nil
+	}
+}
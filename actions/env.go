@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"os"
@@ -16,14 +17,14 @@ var envScript string
 //go:embed embed/.env.template
 var envTemplate string
 
-func (ctx Env) Check() error {
+func (a Env) Check(ctx context.Context) error {
 	if _, err := os.Stat(".env"); os.IsNotExist(err) {
 		return fmt.Errorf("file .env does not exist yet in the current directory (%w)", ErrIncomplete)
 	}
 	return nil
 }
 
-func (ctx Env) Run() error {
+func (a Env) Run(ctx context.Context) error {
 	envScriptFile, err := os.CreateTemp("", ".env.*.sh")
 	if err != nil {
 		return err
@@ -50,7 +51,7 @@ func (ctx Env) Run() error {
 		Stdin: os.Stdin,
 		Env:   append(os.Environ(), fmt.Sprintf("ENV_TEMPLATE=%s", envTemplateFile.Name())),
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
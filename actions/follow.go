@@ -0,0 +1,139 @@
+package actions
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	gh "github.com/google/go-github/v48/github"
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
+)
+
+// Follower can be implemented by actions which wait on a workflow run, so that the run can be followed while the action is polled
+type Follower interface {
+	Follow(ctx context.Context, tail *WorkflowTail) error
+}
+
+// WorkflowTail prints the progress of a workflow run each time it is followed:
+// the status of its jobs and steps when it changes and the log lines it has not printed yet, prefixed with their job and step.
+type WorkflowTail struct {
+	w        io.Writer
+	run      int64
+	statuses map[string]string
+	// lines counts the log lines printed per job
+	lines map[int64]int
+	done  map[int64]bool
+}
+
+func NewWorkflowTail(w io.Writer) *WorkflowTail {
+	return &WorkflowTail{w: w}
+}
+
+// Follow prints what happened in the run of the workflow since it was last followed, see findWorkflowRun for which run that is
+func (t *WorkflowTail) Follow(ctx context.Context, client github.API, entry *journal.Entry, owner, repo, branch, file string) error {
+	run, err := findWorkflowRun(ctx, client, entry, owner, repo, branch, file)
+	if err != nil {
+		return err
+	}
+	if run.GetID() != t.run {
+		t.run = run.GetID()
+		t.statuses = make(map[string]string)
+		t.lines = make(map[int64]int)
+		t.done = make(map[int64]bool)
+		fmt.Fprintf(t.w, "👀 Following %s\n", run.GetHTMLURL())
+	}
+
+	jobs, err := client.GetWorkflowRunJobs(ctx, owner, repo, run.GetID())
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		t.status(job.GetName(), job.GetStatus(), job.GetConclusion())
+		for _, step := range job.Steps {
+			t.status(job.GetName()+"/"+step.GetName(), step.GetStatus(), step.GetConclusion())
+		}
+		if t.done[job.GetID()] || job.GetStatus() == "queued" {
+			continue
+		}
+		err = t.tail(ctx, client, owner, repo, job)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *WorkflowTail) status(name, status, conclusion string) {
+	icon := "⏳"
+	if status == "completed" {
+		status = conclusion
+		switch conclusion {
+		case "success":
+			icon = "✅"
+		case "skipped", "neutral":
+			icon = "⏭️"
+		default:
+			icon = "❌"
+		}
+	}
+	if status == "" || t.statuses[name] == status {
+		return
+	}
+	t.statuses[name] = status
+	fmt.Fprintf(t.w, "[%s] %s %s\n", name, icon, strings.ReplaceAll(status, "_", " "))
+}
+
+// tail prints the lines of the job's logs past the ones printed already, the logs of a job are only available once it started
+func (t *WorkflowTail) tail(ctx context.Context, client github.API, owner, repo string, job *gh.WorkflowJob) error {
+	logs, err := client.GetWorkflowJobLogs(ctx, owner, repo, job.GetID())
+	if err != nil || logs == nil {
+		return err
+	}
+	defer logs.Close()
+
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var step string
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		// every line starts with a timestamp, which tells the step that printed it
+		if ts, rest, ok := strings.Cut(line, " "); ok {
+			if at, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				step = stepAt(job, at)
+				line = rest
+			}
+		}
+		if n <= t.lines[job.GetID()] {
+			continue
+		}
+		prefix := job.GetName()
+		if step != "" {
+			prefix += "/" + step
+		}
+		fmt.Fprintf(t.w, "[%s] %s\n", prefix, line)
+	}
+	if n > t.lines[job.GetID()] {
+		t.lines[job.GetID()] = n
+	}
+	if job.GetStatus() == "completed" {
+		t.done[job.GetID()] = true
+	}
+	return scanner.Err()
+}
+
+// stepAt returns the name of the last step of the job which started before the time
+func stepAt(job *gh.WorkflowJob, at time.Time) string {
+	var name string
+	for _, s := range job.Steps {
+		if s.StartedAt != nil && !s.StartedAt.After(at) {
+			name = s.GetName()
+		}
+	}
+	return name
+}
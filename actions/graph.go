@@ -0,0 +1,199 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/kuboreleaser/util"
+	log "github.com/sirupsen/logrus"
+)
+
+type Node struct {
+	Name  string
+	After []string
+	// Skip reports whether the node is not part of the release of the given version
+	Skip func(version *util.Version) bool
+}
+
+type Graph []Node
+
+// Sort returns the nodes that are part of the release of the given version in an order that satisfies all the dependencies.
+// Nodes that do not depend on each other keep the order in which they were declared.
+// Dependencies on nodes that are skipped for the given version are ignored.
+func (g Graph) Sort(version *util.Version) ([]Node, error) {
+	included := make(map[string]bool)
+	for _, n := range g {
+		if _, ok := included[n.Name]; ok {
+			return nil, fmt.Errorf("🚨 node %s is declared more than once", n.Name)
+		}
+		included[n.Name] = n.Skip == nil || !n.Skip(version)
+	}
+	for _, n := range g {
+		for _, after := range n.After {
+			if _, ok := included[after]; !ok {
+				return nil, fmt.Errorf("🚨 node %s depends on %s which does not exist", n.Name, after)
+			}
+		}
+	}
+
+	var sorted []Node
+	done := make(map[string]bool)
+	for len(sorted) < len(g) {
+		progress := false
+		for _, n := range g {
+			if done[n.Name] {
+				continue
+			}
+			ready := true
+			for _, after := range n.After {
+				if !done[after] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[n.Name] = true
+				sorted = append(sorted, n)
+				progress = true
+			}
+		}
+		if !progress {
+			return nil, fmt.Errorf("🚨 the dependencies between the nodes form a cycle")
+		}
+	}
+
+	var nodes []Node
+	for _, n := range sorted {
+		if included[n.Name] {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+// Subgraph returns the graph restricted to the named nodes.
+// Dependencies which went through the nodes that were left out are preserved.
+func (g Graph) Subgraph(names ...string) (Graph, error) {
+	nodes := make(map[string]Node)
+	for _, n := range g {
+		nodes[n.Name] = n
+	}
+	kept := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := nodes[name]; !ok {
+			return nil, fmt.Errorf("🚨 node %s does not exist", name)
+		}
+		kept[name] = true
+	}
+
+	var after func(name string, visited map[string]bool) []string
+	after = func(name string, visited map[string]bool) []string {
+		var deps []string
+		for _, dep := range nodes[name].After {
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if kept[dep] {
+				deps = append(deps, dep)
+			} else {
+				deps = append(deps, after(dep, visited)...)
+			}
+		}
+		return deps
+	}
+
+	var subgraph Graph
+	for _, n := range g {
+		if kept[n.Name] {
+			n.After = after(n.Name, make(map[string]bool))
+			subgraph = append(subgraph, n)
+		}
+	}
+	return subgraph, nil
+}
+
+// Walk calls fn for every node that is part of the release of the given version, running at most jobs nodes concurrently.
+// A node is only started once all of its dependencies succeeded.
+// If any node fails, no more nodes are started. If it fails with ErrFailure, the context passed to the running nodes is cancelled as well.
+func (g Graph) Walk(ctx context.Context, version *util.Version, jobs int, fn func(context.Context, Node) error) error {
+	nodes, err := g.Sort(version)
+	if err != nil {
+		return err
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	byName := make(map[string]Node)
+	for _, n := range nodes {
+		byName[n.Name] = n
+	}
+	remaining := make(map[string]int)
+	dependents := make(map[string][]string)
+	var queue []string
+	for _, n := range nodes {
+		for _, after := range n.After {
+			if _, ok := byName[after]; ok {
+				remaining[n.Name]++
+				dependents[after] = append(dependents[after], n.Name)
+			}
+		}
+		if remaining[n.Name] == 0 {
+			queue = append(queue, n.Name)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result)
+	started := make(map[string]bool)
+	running := 0
+	var errs []error
+	for {
+		for len(queue) > 0 && running < jobs && len(errs) == 0 && ctx.Err() == nil {
+			n := byName[queue[0]]
+			queue = queue[1:]
+			started[n.Name] = true
+			running++
+			go func() {
+				results <- result{name: n.Name, err: fn(ctx, n)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.name, r.err))
+			if errors.Is(r.err, ErrFailure) {
+				cancel()
+			}
+			continue
+		}
+		for _, d := range dependents[r.name] {
+			remaining[d]--
+			if remaining[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		for _, n := range nodes {
+			if !started[n.Name] {
+				log.Warn("Not executing ", n.Name, " because of the previous failures")
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return ctx.Err()
+}
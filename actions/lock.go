@@ -0,0 +1,257 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	gh "github.com/google/go-github/v48/github"
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/repos"
+	"github.com/ipfs/kuboreleaser/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LockTTL is how long the lock is valid for after the last heartbeat
+	LockTTL       = 15 * time.Minute
+	lockHeartbeat = 5 * time.Minute
+)
+
+var errLockLost = errors.New("the release lock was lost")
+
+var lockMarker = regexp.MustCompile(`<!-- kuboreleaser-lock (.*) -->`)
+
+type lockState struct {
+	Holder    string    `json:"holder"`
+	Heartbeat time.Time `json:"heartbeat"`
+	Expires   time.Time `json:"expires"`
+}
+
+func (s lockState) body() string {
+	b, _ := json.Marshal(s)
+	return fmt.Sprintf(`<!-- kuboreleaser-lock %s -->
+🔒 kuboreleaser is driving this release on behalf of %s. The lock expires at %s unless it is renewed.`, b, s.Holder, s.Expires.UTC().Format(time.RFC3339))
+}
+
+func parseLock(comment *gh.IssueComment) (lockState, bool) {
+	var s lockState
+	m := lockMarker.FindStringSubmatch(comment.GetBody())
+	if m == nil {
+		return s, false
+	}
+	return s, json.Unmarshal([]byte(m[1]), &s) == nil
+}
+
+// Lock is stored as a hidden marker comment on the release issue, so that two maintainers cannot drive the same release at once
+type Lock struct {
+	github github.API
+	issue  int
+	holder string
+
+	mu      sync.Mutex
+	comment int64
+	renewed time.Time
+	err     error
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// AcquireLock takes the lock on the release issue of the version and keeps it alive until Release is called.
+// The release issue is created if it does not exist, which is usually the case for patch releases.
+// A lock held by someone else is only taken over once it expires, or right away if steal is set.
+func AcquireLock(ctx context.Context, github github.API, version *util.Version, holder string, steal bool) (*Lock, error) {
+	number, err := releaseIssueNumber(ctx, github, version)
+	if err != nil {
+		return nil, err
+	}
+	if number == 0 {
+		log.Info("Creating the release issue to keep the release lock on it...")
+		issue, err := github.CreateIssue(ctx, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseIssueTitle(version), fmt.Sprintf("This issue tracks the %s release. kuboreleaser created it to keep the release lock on it.", version))
+		if err != nil {
+			return nil, err
+		}
+		number = issue.GetNumber()
+		rememberReleaseIssue(version, number)
+	}
+
+	l := &Lock{
+		github: github,
+		issue:  number,
+		holder: holder,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	comments, err := l.comments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if len(comments) == 0 {
+		c, err := github.CreateIssueComment(ctx, repos.Kubo.Owner, repos.Kubo.Repo, l.issue, l.state(now).body())
+		if err != nil {
+			return nil, err
+		}
+		l.comment = c.GetID()
+		l.renewed = now
+
+		// if someone else created their lock at the same time, the older one wins
+		comments, err = l.comments(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(comments) > 0 && comments[0].GetID() != l.comment {
+			err = github.DeleteIssueComment(ctx, repos.Kubo.Owner, repos.Kubo.Repo, l.comment)
+			if err != nil {
+				log.Warn("Failed to delete the lock comment: ", err)
+			}
+			s, _ := parseLock(comments[0])
+			return nil, fmt.Errorf("🚨 the release is locked by %s, see %s", s.Holder, comments[0].GetHTMLURL())
+		}
+	} else {
+		s, _ := parseLock(comments[0])
+		l.comment = comments[0].GetID()
+		switch {
+		case s.Holder == holder:
+			log.Debug("We hold the release lock already")
+		case now.After(s.Expires):
+			log.Info("Taking over the release lock from ", s.Holder, " which expired at ", s.Expires)
+		case steal:
+			log.Warn("⚠️ Stealing the release lock from ", s.Holder)
+		default:
+			return nil, fmt.Errorf("🚨 the release is locked by %s until %s, see %s (pass --steal-lock if you are sure they are not working on it)", s.Holder, s.Expires.Local().Format(time.RFC1123), comments[0].GetHTMLURL())
+		}
+		err = l.heartbeat(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.WithField("issue", util.GitHubURL("%s/%s/issues/%d", repos.Kubo.Owner, repos.Kubo.Repo, number)).Info("🔒 Acquired the release lock as ", holder)
+	go l.keepAlive()
+	return l, nil
+}
+
+func (l *Lock) state(now time.Time) lockState {
+	return lockState{
+		Holder:    l.holder,
+		Heartbeat: now,
+		Expires:   now.Add(LockTTL),
+	}
+}
+
+// comments returns the lock comments on the release issue, the oldest first
+func (l *Lock) comments(ctx context.Context) ([]*gh.IssueComment, error) {
+	all, err := l.github.GetIssueComments(ctx, repos.Kubo.Owner, repos.Kubo.Repo, l.issue)
+	if err != nil {
+		return nil, err
+	}
+	var comments []*gh.IssueComment
+	for _, c := range all {
+		if _, ok := parseLock(c); ok {
+			comments = append(comments, c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].GetID() < comments[j].GetID()
+	})
+	return comments, nil
+}
+
+// heartbeat renews the lock, failing if someone else took it over in the meantime.
+// With takeover set, the lock is overwritten even if someone else holds it.
+// Only the lock comment found when acquiring the lock is fetched, an older lock comment cannot appear later on.
+func (l *Lock) heartbeat(ctx context.Context, takeover bool) error {
+	comment, err := l.github.GetIssueCommentByID(ctx, repos.Kubo.Owner, repos.Kubo.Repo, l.comment)
+	if err != nil {
+		return err
+	}
+	s, ok := lockState{}, false
+	if comment != nil {
+		s, ok = parseLock(comment)
+	}
+	if !ok {
+		return fmt.Errorf("🚨 the release lock comment was removed (%w)", errLockLost)
+	}
+	if s.Holder != l.holder && !takeover {
+		return fmt.Errorf("🚨 the release lock was taken over by %s, see %s (%w)", s.Holder, comment.GetHTMLURL(), errLockLost)
+	}
+
+	now := time.Now()
+	_, err = l.github.UpdateIssueComment(ctx, repos.Kubo.Owner, repos.Kubo.Repo, l.comment, l.state(now).body())
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.renewed = now
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *Lock) keepAlive() {
+	defer close(l.done)
+	ticker := time.NewTicker(lockHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			err := l.heartbeat(ctx, false)
+			cancel()
+			if err != nil {
+				l.mu.Lock()
+				// a failed heartbeat is retried as long as the lock has not expired
+				lost := errors.Is(err, errLockLost) || time.Since(l.renewed) > LockTTL
+				if lost {
+					l.err = err
+				}
+				l.mu.Unlock()
+				if lost {
+					log.Error(err)
+					return
+				}
+				log.Warn("Failed to renew the release lock: ", err)
+				continue
+			}
+			log.Debug("Renewed the release lock")
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Held returns an error unless we still hold the lock
+func (l *Lock) Held() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return fmt.Errorf("%w, refusing to make any more changes (%w)", l.err, ErrFailure)
+	}
+	return nil
+}
+
+// Release stops renewing the lock and removes it from the release issue
+func (l *Lock) Release(ctx context.Context) {
+	close(l.stop)
+	<-l.done
+	l.mu.Lock()
+	lost := l.err != nil
+	l.mu.Unlock()
+	if lost {
+		return
+	}
+
+	err := l.github.DeleteIssueComment(ctx, repos.Kubo.Owner, repos.Kubo.Repo, l.comment)
+	if err != nil {
+		log.Warn("Failed to release the lock: ", err)
+		return
+	}
+	log.Info("🔓 Released the release lock")
+}
@@ -1,44 +1,53 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type MergeBranch struct {
-	GitHub  *github.Client
-	Version *util.Version
+	GitHub   github.API
+	Version  *util.Version
+	Prompter Prompter
 }
 
-func (ctx MergeBranch) Check() error {
-	log.Info("I'm going to check if the PR that merges the release branch to master exists and if it's merged already.")
+func (a MergeBranch) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the PR that merges the release branch to master exists and if it's merged already.")
 
-	return CheckPR(ctx.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseMergeBranch(ctx.Version), true)
+	return CheckPR(ctx, a.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseMergeBranch(a.Version), true)
 }
 
-func (ctx MergeBranch) Run() error {
-	log.Info("I'm going to create a PR that merges the release branch to master and ask you to merge it for me.")
+func (a MergeBranch) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a PR that merges the release branch to master and ask you to merge it for me.")
 
-	branch := repos.Kubo.ReleaseMergeBranch(ctx.Version)
-	title := fmt.Sprintf("Merge Release: %s [skip changelog]", ctx.Version)
-	body := fmt.Sprintf("This PR merges the release branch %s to %s", ctx.Version, repos.Kubo.DefaultBranch)
+	branch := repos.Kubo.ReleaseMergeBranch(a.Version)
+	title := fmt.Sprintf("Merge Release: %s [skip changelog]", a.Version)
+	body := withReleaseIssueRef(ctx, a.GitHub, a.Version, fmt.Sprintf("This PR merges the release branch %s to %s", a.Version, repos.Kubo.DefaultBranch))
 
-	_, err := ctx.GitHub.GetOrCreateBranch(repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.ReleaseBranch)
+	_, err := a.GitHub.GetOrCreateBranch(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.ReleaseBranch)
 	if err != nil {
 		return err
 	}
 
-	pr, err := ctx.GitHub.GetOrCreatePR(repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.DefaultBranch, title, body, false)
+	pr, err := a.GitHub.GetOrCreatePR(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.DefaultBranch, title, body, false)
 	if err != nil {
 		return err
 	}
-	if !util.ConfirmPR(pr) {
+	assignReviewers(ctx, a.GitHub, pr)
+	// the release history has to be preserved on master
+	enableAutoMerge(ctx, a.GitHub, pr, github.MergeMethodMerge)
+	if !Confirm(ctx, a.Prompter, PRPrompt("merge-branch/merge-pr", pr)) {
 		return fmt.Errorf("🚨 %s not merged", pr.GetHTMLURL())
 	}
 
 	return nil
 }
+
+func (a MergeBranch) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
+}
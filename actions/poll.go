@@ -0,0 +1,88 @@
+package actions
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Poll describes how long to wait between the checks which follow the run of an action
+type Poll struct {
+	InitialDelay time.Duration
+	Interval     time.Duration
+	Multiplier   float64
+	MaxInterval  time.Duration
+	// Jitter is the fraction by which each interval is randomly shortened or extended
+	Jitter float64
+	// Deadline is the overall time after which we give up waiting, 0 means no deadline
+	Deadline time.Duration
+	// Retries is how many times the actions which implement Retrier retry what failed before giving up
+	Retries int
+}
+
+var DefaultPoll = Poll{
+	InitialDelay: 10 * time.Second,
+	Interval:     time.Minute,
+	Multiplier:   1.5,
+	MaxInterval:  5 * time.Minute,
+	Jitter:       0.1,
+	Deadline:     6 * time.Hour,
+}
+
+// WorkflowPoll is meant for actions which wait for a GitHub Actions workflow run
+var WorkflowPoll = Poll{
+	InitialDelay: 30 * time.Second,
+	Interval:     30 * time.Second,
+	Multiplier:   1.5,
+	MaxInterval:  3 * time.Minute,
+	Jitter:       0.1,
+	Deadline:     time.Hour,
+	// most failures are caused by flaky network steps
+	Retries: 2,
+}
+
+// Poller can be implemented by actions which need a different poll strategy than DefaultPoll
+type Poller interface {
+	Poll() Poll
+}
+
+func GetPoll(action IAction) Poll {
+	if p, ok := action.(Poller); ok {
+		return p.Poll()
+	}
+	return DefaultPoll
+}
+
+// Delay returns how long to wait before the given check, counting from 0
+func (p Poll) Delay(attempt int) time.Duration {
+	if attempt == 0 {
+		return p.InitialDelay
+	}
+
+	delay := float64(p.Interval) * math.Pow(math.Max(p.Multiplier, 1), float64(attempt-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	// the checks are never closer than a second apart, whatever the jitter
+	return time.Duration(math.Max(delay, float64(time.Second))).Round(time.Second)
+}
+
+// ValidateInterval rejects the intervals which would turn the poll into a busy loop
+func ValidateInterval(interval time.Duration) error {
+	if interval < time.Second {
+		return fmt.Errorf("🚨 the poll interval must be at least 1s, got %s", interval)
+	}
+	return nil
+}
+
+// ValidateJitter rejects the jitters which could shorten an interval to nothing
+func ValidateJitter(jitter float64) error {
+	if jitter < 0 || jitter >= 1 {
+		return fmt.Errorf("🚨 the poll jitter must be at least 0 and less than 1, got %g", jitter)
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package actions
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -11,31 +13,33 @@ import (
 	gh "github.com/google/go-github/v48/github"
 	"github.com/ipfs/kuboreleaser/git"
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
 	log "github.com/sirupsen/logrus"
 )
 
 type PrepareBranch struct {
-	Git     *git.Client
-	GitHub  *github.Client
-	Version *util.Version
+	Git      *git.Client
+	GitHub   github.API
+	Version  *util.Version
+	Prompter Prompter
 }
 
-func (ctx PrepareBranch) Check() error {
-	log.Info("I'm going to check if PRs that update versions in the release branch and the master branch exist and if they're merged already.")
+func (a PrepareBranch) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if PRs that update versions in the release branch and the master branch exist and if they're merged already.")
 
-	versionReleaseBranch := repos.Kubo.VersionReleaseBranch(ctx.Version)
+	versionReleaseBranch := repos.Kubo.VersionReleaseBranch(a.Version)
 
-	err := CheckPR(ctx.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, versionReleaseBranch, !ctx.Version.IsPrerelease())
+	err := CheckPR(ctx, a.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, versionReleaseBranch, !a.Version.IsPrerelease())
 	if err != nil {
 		return err
 	}
 	// Should we check if the PR checks are passing?
 
-	if !ctx.Version.IsPatch() {
-		versionUpdateBranch := repos.Kubo.VersionUpdateBranch(ctx.Version)
-		err := CheckPR(ctx.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, versionUpdateBranch, true)
+	if !a.Version.IsPatch() {
+		versionUpdateBranch := repos.Kubo.VersionUpdateBranch(a.Version)
+		err := CheckPR(ctx, a.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, versionUpdateBranch, true)
 		if err != nil {
 			return err
 		}
@@ -44,18 +48,33 @@ func (ctx PrepareBranch) Check() error {
 	return nil
 }
 
-func (ctx PrepareBranch) MkReleaseLog() error {
+func (a PrepareBranch) MkReleaseLog(ctx context.Context) error {
 	placeholder := []byte("### 📝 Changelog\n\n### 👨‍👩‍👧‍👦 Contributors\n")
 	rootname := "/root/go/src"
 	dirname := fmt.Sprintf("%s/github.com/%s/%s", rootname, repos.Kubo.Owner, repos.Kubo.Repo)
-	filename := fmt.Sprintf("docs/changelogs/%s.md", ctx.Version.MajorMinor())
-	branch := repos.Kubo.VersionReleaseBranch(ctx.Version)
+	filename := fmt.Sprintf("docs/changelogs/%s.md", a.Version.MajorMinor())
+	branch := repos.Kubo.VersionReleaseBranch(a.Version)
+
+	if a.Git.Plan(fmt.Sprintf("generate the release log with ./bin/mkreleaselog and push it to %s in %s/%s/%s", branch, util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo), log.Fields{
+		"branch":   branch,
+		"filename": filename,
+	}) {
+		return nil
+	}
 
 	name := util.GetenvPrompt("GITHUB_USER_NAME")
 	email := util.GetenvPrompt("GITHUB_USER_EMAIL")
 	token := util.GetenvPromptSecret("GITHUB_TOKEN", "The token should have the following scopes: ... Please enter the token:")
 
-	err := os.MkdirAll(rootname, 0755)
+	remote, err := url.Parse(util.GitHubRemote(repos.Kubo.Owner, repos.Kubo.Repo))
+	if err != nil {
+		return err
+	}
+	if remote.Scheme == "https" || remote.Scheme == "http" {
+		remote.User = url.User(token)
+	}
+
+	err = os.MkdirAll(rootname, 0755)
 	if err != nil {
 		return err
 	}
@@ -63,9 +82,9 @@ func (ctx PrepareBranch) MkReleaseLog() error {
 
 	cmd := util.Command{
 		Name: "git",
-		Args: []string{"clone", fmt.Sprintf("https://%s@github.com/ipfs/kubo", token), dirname},
+		Args: []string{"clone", remote.String(), dirname},
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -75,7 +94,7 @@ func (ctx PrepareBranch) MkReleaseLog() error {
 		Args: []string{"config", "user.name", name},
 		Dir:  dirname,
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -85,7 +104,7 @@ func (ctx PrepareBranch) MkReleaseLog() error {
 		Args: []string{"config", "user.email", email},
 		Dir:  dirname,
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -103,7 +122,7 @@ func (ctx PrepareBranch) MkReleaseLog() error {
 		Args: []string{"checkout", branch},
 		Dir:  dirname,
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -116,7 +135,7 @@ func (ctx PrepareBranch) MkReleaseLog() error {
 			Writer: out,
 		},
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -131,17 +150,17 @@ func (ctx PrepareBranch) MkReleaseLog() error {
 		Args: []string{"add", filename},
 		Dir:  dirname,
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
 
 	cmd = util.Command{
 		Name: "git",
-		Args: []string{"commit", "-m", fmt.Sprintf("chore: update changelog for %s", ctx.Version.MajorMinor())},
+		Args: []string{"commit", "-m", fmt.Sprintf("chore: update changelog for %s", a.Version.MajorMinor())},
 		Dir:  dirname,
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -151,7 +170,7 @@ func (ctx PrepareBranch) MkReleaseLog() error {
 		Args: []string{"push", "origin", branch},
 		Dir:  dirname,
 	}
-	err = cmd.Run()
+	err = cmd.Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -159,35 +178,36 @@ func (ctx PrepareBranch) MkReleaseLog() error {
 	return nil
 }
 
-func (ctx PrepareBranch) UpdateVersion(branch, source, currentVersionNumber, base, title, body string, draft bool) (*gh.PullRequest, error) {
-	b, err := ctx.GitHub.GetOrCreateBranch(repos.Kubo.Owner, repos.Kubo.Repo, branch, source)
+func (a PrepareBranch) UpdateVersion(ctx context.Context, branch, source, currentVersionNumber, base, title, body string, draft bool) (*gh.PullRequest, error) {
+	b, err := a.GitHub.GetOrCreateBranch(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, source)
 	if err != nil {
 		return nil, err
 	}
 
-	err = ctx.Git.RunAndPush(repos.Kubo.Owner, repos.Kubo.Repo, branch, b.GetCommit().GetSHA(), "chore: update version", util.Command{Name: "sed", Args: []string{"-i", fmt.Sprintf("s/const CurrentVersionNumber = \".*\"/const CurrentVersionNumber = \"%s\"/g", currentVersionNumber), "version.go"}})
+	err = a.Git.RunAndPush(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, b.GetCommit().GetSHA(), "chore: update version", util.Command{Name: "sed", Args: []string{"-i", fmt.Sprintf("s/const CurrentVersionNumber = \".*\"/const CurrentVersionNumber = \"%s\"/g", currentVersionNumber), "version.go"}})
 	if err != nil {
 		return nil, err
 	}
 
-	pr, err := ctx.GitHub.GetOrCreatePR(repos.Kubo.Owner, repos.Kubo.Repo, branch, base, title, body, draft)
+	pr, err := a.GitHub.GetOrCreatePR(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, base, title, body, draft)
 	if err != nil {
 		return nil, err
 	}
+	assignReviewers(ctx, a.GitHub, pr)
 	return pr, nil
 }
 
-func (ctx PrepareBranch) GetBody(branch, foreword string) (string, error) {
-	kuboCommits, err := ctx.GitHub.Compare(repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.DefaultBranch)
+func (a PrepareBranch) GetBody(ctx context.Context, branch, foreword string) (string, error) {
+	kuboCommits, err := a.GitHub.Compare(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.DefaultBranch)
 	if err != nil {
 		return "", err
 	}
-	file, err := ctx.GitHub.GetFile(repos.Kubo.Owner, repos.Kubo.Repo, "go.mod", branch)
+	file, err := a.GitHub.GetFile(ctx, repos.Kubo.Owner, repos.Kubo.Repo, "go.mod", branch)
 	if err != nil {
 		return "", err
 	}
 	if file == nil {
-		return "", fmt.Errorf("🚨 https://github.com/%s/%s/tree/%s/go.mod not found", repos.Kubo.Owner, repos.Kubo.Repo, branch)
+		return "", fmt.Errorf("🚨 %s/%s/%s/tree/%s/go.mod not found", util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, branch)
 	}
 
 	content, err := base64.StdEncoding.DecodeString(*file.Content)
@@ -205,7 +225,7 @@ func (ctx PrepareBranch) GetBody(branch, foreword string) (string, error) {
 		}
 	}
 	if boxoVersion == "" {
-		return "", fmt.Errorf("🚨 boxo version not found in https://github.com/%s/%s/tree/%s/go.mod", repos.Kubo.Owner, repos.Kubo.Repo, branch)
+		return "", fmt.Errorf("🚨 boxo version not found in %s/%s/%s/tree/%s/go.mod", util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, branch)
 	}
 
 	// find the boxo commit or tag in boxo version
@@ -216,7 +236,7 @@ func (ctx PrepareBranch) GetBody(branch, foreword string) (string, error) {
 		boxoBranch = boxoVersion
 	}
 
-	boxoCommits, err := ctx.GitHub.Compare(repos.Boxo.Owner, repos.Boxo.Repo, boxoBranch, repos.Boxo.DefaultBranch)
+	boxoCommits, err := a.GitHub.Compare(ctx, repos.Boxo.Owner, repos.Boxo.Repo, boxoBranch, repos.Boxo.DefaultBranch)
 	if err != nil {
 		return "", err
 	}
@@ -246,22 +266,22 @@ func (ctx PrepareBranch) GetBody(branch, foreword string) (string, error) {
 %s`, foreword, kuboCommitsStr, boxoCommitsStr), nil
 }
 
-func (ctx PrepareBranch) Run() error {
-	log.Info("I'm going to create PRs that update the version in the release branch and the master branch.")
-	log.Info("I'm also going to update the changelog if we're performing the final release. Please note that it might take a while because I have to clone a looooot of repos.")
+func (a PrepareBranch) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create PRs that update the version in the release branch and the master branch.")
+	util.Logger(ctx).Info("I'm also going to update the changelog if we're performing the final release. Please note that it might take a while because I have to clone a looooot of repos.")
 
-	dev := fmt.Sprintf("%s.0-dev", ctx.Version.NextMajorMinor())
+	dev := fmt.Sprintf("%s.0-dev", a.Version.NextMajorMinor())
 
-	branch := repos.Kubo.VersionReleaseBranch(ctx.Version)
+	branch := repos.Kubo.VersionReleaseBranch(a.Version)
 	var source string
-	if ctx.Version.IsPatch() {
+	if a.Version.IsPatch() {
 		// NOTE: For patch releases we want to create the new release branch from the previous release branch, e.g.
 		// when creating release-0.50.6, we want to create it from release-0.50.5
-		patchVersion, err := strconv.Atoi(ctx.Version.Patch())
+		patchVersion, err := strconv.Atoi(a.Version.Patch())
 		if err != nil {
 			return err
 		}
-		previousVersionString := fmt.Sprintf("%s.%s", ctx.Version.MajorMinor(), strconv.Itoa(patchVersion-1))
+		previousVersionString := fmt.Sprintf("%s.%s", a.Version.MajorMinor(), strconv.Itoa(patchVersion-1))
 		previousVersion, err := util.NewVersion(previousVersionString)
 		if err != nil {
 			return err
@@ -270,31 +290,31 @@ func (ctx PrepareBranch) Run() error {
 	} else {
 		source = repos.Kubo.DefaultBranch
 	}
-	currentVersionNumber := ctx.Version.String()[1:]
+	currentVersionNumber := a.Version.String()[1:]
 	base := repos.Kubo.ReleaseBranch
-	title := fmt.Sprintf("Release: %s [skip changelog]", ctx.Version.MajorMinorPatch())
-	body := fmt.Sprintf("This PR creates release %s", ctx.Version.MajorMinorPatch())
-	draft := ctx.Version.IsPrerelease()
+	title := fmt.Sprintf("Release: %s [skip changelog]", a.Version.MajorMinorPatch())
+	body := withReleaseIssueRef(ctx, a.GitHub, a.Version, fmt.Sprintf("This PR creates release %s", a.Version.MajorMinorPatch()))
+	draft := a.Version.IsPrerelease()
 
 	// NOTE: This should update const CurrentVersionNumber in version.go to the full version without a v prefix
 	// on the version release branch created from source
-	pr, err := ctx.UpdateVersion(branch, source, currentVersionNumber, base, title, body, draft)
+	pr, err := a.UpdateVersion(ctx, branch, source, currentVersionNumber, base, title, body, draft)
 	if err != nil {
 		return err
 	}
 
-	body, err = ctx.GetBody(branch, body)
+	body, err = a.GetBody(ctx, branch, body)
 	if err != nil {
 		return err
 	}
 
 	pr.Body = &body
-	err = ctx.GitHub.UpdatePR(pr)
+	err = a.GitHub.UpdatePR(ctx, pr)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("💁 Your release PR is ready at %s\n", pr.GetHTMLURL())
+	util.Logger(ctx).WithField("url", pr.GetHTMLURL()).Info("💁 Your release PR is ready")
 
 	// TODO: check for conflicts and tell the user to resolve them
 	// or resolve them automatically with git merge origin/release -X ours
@@ -304,42 +324,53 @@ func (ctx PrepareBranch) Run() error {
 git cherry-pick -x <commit>
 
 Please approve after all the required commits are cherry-picked.`, branch, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DefaultBranch)
-	if !util.Confirm(prompt) {
-		return fmt.Errorf("🚨 cherry-picking commits to https://github.com/%s/%s/tree/%s was not confirmed correctly", repos.Kubo.Owner, repos.Kubo.Repo, branch)
+	if !Confirm(ctx, a.Prompter, Prompt{ID: "prepare-branch/cherry-pick", Message: prompt}) {
+		return fmt.Errorf("🚨 cherry-picking commits to %s/%s/%s/tree/%s was not confirmed correctly", util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, branch)
 	}
 
-	if !ctx.Version.IsPrerelease() {
-		err := ctx.MkReleaseLog()
+	if !a.Version.IsPrerelease() {
+		err := a.MkReleaseLog(ctx)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println("Use merge commit to merge this PR! You'll have to tag it after the merge.")
-		if !util.ConfirmPR(pr) {
+		// the release PR is only set to merge itself once its contents are final, and the tag has to point at a merge commit
+		if !enableAutoMerge(ctx, a.GitHub, pr, github.MergeMethodMerge) {
+			util.Logger(ctx).WithField("url", pr.GetHTMLURL()).Warn("⚠️ Use merge commit to merge this PR! You'll have to tag it after the merge.")
+		}
+		if !Confirm(ctx, a.Prompter, PRPrompt("prepare-branch/merge-release-pr", pr)) {
 			return fmt.Errorf("🚨 %s not merged", pr.GetHTMLURL())
 		}
 	}
 
-	if !ctx.Version.IsPatch() {
-		branch = repos.Kubo.VersionUpdateBranch(ctx.Version)
+	if !a.Version.IsPatch() {
+		branch = repos.Kubo.VersionUpdateBranch(a.Version)
 		source = repos.Kubo.DefaultBranch
 		currentVersionNumber = dev[1:]
 		base = repos.Kubo.DefaultBranch
-		title = fmt.Sprintf("Update Version: %s [skip changelog]", ctx.Version.MajorMinor())
-		body = fmt.Sprintf("This PR updates version as part of the %s release", ctx.Version.MajorMinor())
+		title = fmt.Sprintf("Update Version: %s [skip changelog]", a.Version.MajorMinor())
+		body = withReleaseIssueRef(ctx, a.GitHub, a.Version, fmt.Sprintf("This PR updates version as part of the %s release", a.Version.MajorMinor()))
 		draft = false
 
-		pr, err := ctx.UpdateVersion(branch, source, currentVersionNumber, base, title, body, draft)
+		pr, err := a.UpdateVersion(ctx, branch, source, currentVersionNumber, base, title, body, draft)
 		if err != nil {
 			return err
 		}
 
-		if ctx.Version.IsPrerelease() {
-			fmt.Printf(`💁 Release PR ready at %s. Do not merge it.`, pr.GetHTMLURL())
-		} else if !pr.GetMerged() && !util.ConfirmPR(pr) {
+		if !a.Version.IsPrerelease() {
+			enableAutoMerge(ctx, a.GitHub, pr, github.MergeMethodSquash)
+		}
+
+		if a.Version.IsPrerelease() {
+			util.Logger(ctx).WithField("url", pr.GetHTMLURL()).Info("💁 Release PR ready. Do not merge it.")
+		} else if !pr.GetMerged() && !Confirm(ctx, a.Prompter, PRPrompt("prepare-branch/merge-version-update-pr", pr)) {
 			return fmt.Errorf("🚨 %s not merged", pr.GetHTMLURL())
 		}
 	}
 
 	return nil
 }
+
+func (a PrepareBranch) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
+}
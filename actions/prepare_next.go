@@ -1,45 +1,47 @@
 package actions
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"strings"
 
 	"github.com/ipfs/kuboreleaser/git"
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type PrepareNext struct {
-	Git     *git.Client
-	GitHub  *github.Client
-	Version *util.Version
+	Git      *git.Client
+	GitHub   github.API
+	Version  *util.Version
+	Prompter Prompter
 }
 
-func (ctx PrepareNext) getNextVersion() *util.Version {
-	version, _ := util.NewVersion(ctx.Version.NextMajorMinor())
+func (a PrepareNext) getNextVersion() *util.Version {
+	version, _ := util.NewVersion(a.Version.NextMajorMinor())
 	return version
 }
 
-func (ctx PrepareNext) Check() error {
-	log.Info("I'm going to check if the PR that creates the next changelog exists and if it's merged already.")
-	log.Info("I'm also going to check if the next release issue exists already.")
+func (a PrepareNext) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the PR that creates the next changelog exists and if it's merged already.")
+	util.Logger(ctx).Info("I'm also going to check if the next release issue exists already.")
 
-	next := ctx.getNextVersion()
+	next := a.getNextVersion()
 	branch := repos.Kubo.ChangelogBranch(next)
 	title := repos.Kubo.ReleaseIssueTitle(next)
 
-	issue, err := ctx.GitHub.GetIssue(repos.Kubo.Owner, repos.Kubo.Repo, title)
+	issue, err := a.GitHub.GetIssue(ctx, repos.Kubo.Owner, repos.Kubo.Repo, title)
 	if err != nil {
 		return err
 	}
 	if issue == nil {
-		return fmt.Errorf("⚠️ issue '%s' not found in https://github.com/%s/%s/issues (%w)", title, repos.Kubo.Owner, repos.Kubo.Repo, ErrIncomplete)
+		return fmt.Errorf("⚠️ issue '%s' not found in %s/%s/%s/issues (%w)", title, util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, ErrIncomplete)
 	}
 
-	err = CheckPR(ctx.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, branch, true)
+	err = CheckPR(ctx, a.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, branch, true)
 	if err != nil {
 		return err
 	}
@@ -47,16 +49,16 @@ func (ctx PrepareNext) Check() error {
 	return nil
 }
 
-func (ctx PrepareNext) Run() error {
-	log.Info("I'm going to create a PR that creates the next changelog and ask you to merge it for me.")
-	log.Info("I'm also going to create the next release issue.")
+func (a PrepareNext) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a PR that creates the next changelog and ask you to merge it for me.")
+	util.Logger(ctx).Info("I'm also going to create the next release issue.")
 
-	file, err := ctx.GitHub.GetFile(repos.Kubo.Owner, repos.Kubo.Repo, "docs/RELEASE_ISSUE_TEMPLATE.md", repos.Kubo.DefaultBranch)
+	file, err := a.GitHub.GetFile(ctx, repos.Kubo.Owner, repos.Kubo.Repo, "docs/RELEASE_ISSUE_TEMPLATE.md", repos.Kubo.DefaultBranch)
 	if err != nil {
 		return err
 	}
 	if file == nil {
-		return fmt.Errorf("🚨 https://github.com/%s/%s/tree/%s/docs/RELEASE_ISSUE_TEMPLATE.md not found", repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DefaultBranch)
+		return fmt.Errorf("🚨 %s/%s/%s/tree/%s/docs/RELEASE_ISSUE_TEMPLATE.md not found", util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DefaultBranch)
 	}
 
 	content, err := base64.StdEncoding.DecodeString(*file.Content)
@@ -64,21 +66,21 @@ func (ctx PrepareNext) Run() error {
 		return err
 	}
 
-	next := ctx.getNextVersion()
+	next := a.getNextVersion()
 	branch := repos.Kubo.ChangelogBranch(next)
 	issueTitle := repos.Kubo.ReleaseIssueTitle(next)
 	issueBody := string(content)
 	issueBody = strings.ReplaceAll(issueBody, "vX.Y.Z", next.MajorMinorPatch())
 	issueBody = strings.ReplaceAll(issueBody, "vX.Y", next.MajorMinor())
 	prTitle := fmt.Sprintf("Create Changelog: %s", next.MajorMinor())
-	prBody := fmt.Sprintf("This PR creates changelog: %s", next.MajorMinor())
+	prBody := withReleaseIssueRef(ctx, a.GitHub, a.Version, fmt.Sprintf("This PR creates changelog: %s", next.MajorMinor()))
 
-	_, err = ctx.GitHub.GetOrCreateIssue(repos.Kubo.Owner, repos.Kubo.Repo, issueTitle, issueBody)
+	_, err = a.GitHub.GetOrCreateIssue(ctx, repos.Kubo.Owner, repos.Kubo.Repo, issueTitle, issueBody)
 	if err != nil {
 		return err
 	}
 
-	b, err := ctx.GitHub.GetOrCreateBranch(repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.DefaultBranch)
+	b, err := a.GitHub.GetOrCreateBranch(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.DefaultBranch)
 	if err != nil {
 		return err
 	}
@@ -118,19 +120,25 @@ func (ctx PrepareNext) Run() error {
 			fi
 		`, next.MajorMinor(), next.MajorMinor(), next.MajorMinor())},
 	}
-	err = ctx.Git.RunAndPush(repos.Kubo.Owner, repos.Kubo.Repo, branch, b.GetCommit().GetSHA(), "chore: create next changelog", createChangelog, linkChangelog)
+	err = a.Git.RunAndPush(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, b.GetCommit().GetSHA(), "chore: create next changelog", createChangelog, linkChangelog)
 	if err != nil {
 		return err
 	}
 
-	pr, err := ctx.GitHub.GetOrCreatePR(repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.DefaultBranch, prTitle, prBody, false)
+	pr, err := a.GitHub.GetOrCreatePR(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch, repos.Kubo.DefaultBranch, prTitle, prBody, false)
 	if err != nil {
 		return err
 	}
+	assignReviewers(ctx, a.GitHub, pr)
+	enableAutoMerge(ctx, a.GitHub, pr, github.MergeMethodSquash)
 
-	if !util.ConfirmPR(pr) {
+	if !Confirm(ctx, a.Prompter, PRPrompt("prepare-next/merge-pr", pr)) {
 		return fmt.Errorf("🚨 %s not merged", pr.GetHTMLURL())
 	}
 
 	return nil
 }
+
+func (a PrepareNext) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
+}
@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -9,43 +10,49 @@ import (
 	"strings"
 
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/matrix"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type Promote struct {
-	GitHub  *github.Client
-	Matrix  *matrix.Client
-	Version *util.Version
+	GitHub   github.API
+	Matrix   *matrix.Client
+	Version  *util.Version
+	Prompter Prompter
 }
 
-func (ctx *Promote) getDiscoursePostTitle() string {
-	return fmt.Sprintf("Kubo %s is out!", ctx.Version)
+func (a *Promote) getDiscoursePostTitle() string {
+	return fmt.Sprintf("Kubo %s is out!", a.Version)
 }
 
-func (ctx *Promote) getDiscoursePostBody() string {
+func (a *Promote) getDiscoursePostBody() string {
 	return fmt.Sprintf(`## Kubo %s is out!
 
 See:
-- Code: https://github.com/ipfs/kubo/releases/tag/%s
+- Code: %s
 - Binaries: https://dist.ipfs.tech/kubo/%s/
 - Docker: `+"`docker pull ipfs/kubo:%s`"+`
-- Release Notes: https://github.com/ipfs/kubo/blob/release-%s/docs/changelogs/%s.md`, ctx.Version, ctx.Version, ctx.Version, ctx.Version, ctx.Version.MajorMinorPatch(), ctx.Version.MajorMinor())
+- Release Notes: %s`, a.Version, repos.Kubo.ReleaseURL(a.Version), a.Version, a.Version, util.GitHubURL("%s/%s/blob/release-%s/docs/changelogs/%s.md", repos.Kubo.Owner, repos.Kubo.Repo, a.Version.MajorMinorPatch(), a.Version.MajorMinor()))
 }
 
-func fetchEarlyTestersList() string {
+func fetchEarlyTestersList(ctx context.Context) string {
 	url := "https://raw.githubusercontent.com/ipfs/kubo/master/docs/EARLY_TESTERS.md"
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		log.Warn("Error fetching EARLY_TESTERS.md:", err)
+		util.Logger(ctx).Warn("Error fetching EARLY_TESTERS.md:", err)
+		return ""
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		util.Logger(ctx).Warn("Error fetching EARLY_TESTERS.md:", err)
 		return ""
 	}
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Warn("Error reading EARLY_TESTERS.md:", err)
+		util.Logger(ctx).Warn("Error reading EARLY_TESTERS.md:", err)
 		return ""
 	}
 	content := string(body)
@@ -54,7 +61,7 @@ func fetchEarlyTestersList() string {
 	re := regexp.MustCompile(`(?s)## Who has signed up\?(.+?)(?:$|##)`)
 	matches := re.FindStringSubmatch(content)
 	if len(matches) < 2 {
-		log.Warn("'Who has signed up' Section not found in EARLY_TESTERS.md")
+		util.Logger(ctx).Warn("'Who has signed up' Section not found in EARLY_TESTERS.md")
 		return ""
 	}
 	testers := strings.TrimSpace(matches[1])
@@ -62,42 +69,42 @@ func fetchEarlyTestersList() string {
 	return testers
 }
 
-func (ctx *Promote) getReleaseIssueComment() string {
-	testers := fetchEarlyTestersList()
-	if ctx.Version.IsPrerelease() && testers != "" {
+func (a *Promote) getReleaseIssueComment(ctx context.Context) string {
+	testers := fetchEarlyTestersList(ctx)
+	if a.Version.IsPrerelease() && testers != "" {
 		return fmt.Sprintf(`Early testers ping for %s testing ✨
 
 %s
 
-You're getting this message because you're listed [here](https://github.com/ipfs/kubo/blob/master/docs/EARLY_TESTERS.md#who-has-signed-up). Please update this list if you no longer want to be included.`, ctx.Version, testers)
+You're getting this message because you're listed [here](%s). Please update this list if you no longer want to be included.`, a.Version, testers, util.GitHubURL("%s/%s/blob/%s/docs/EARLY_TESTERS.md#who-has-signed-up", repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DefaultBranch))
 	} else {
-		return fmt.Sprintf("🎉 Kubo [%s](https://github.com/ipfs/kubo/releases/tag/%s) is out!", ctx.Version, ctx.Version)
+		return fmt.Sprintf("🎉 Kubo [%s](%s) is out!", a.Version, repos.Kubo.ReleaseURL(a.Version))
 	}
 }
 
-func (ctx Promote) Check() error {
-	log.Info("I'm going to check if the release was promoted on GitHub, Discourse, Twitter and Reddit.")
+func (a Promote) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the release was promoted on GitHub, Discourse, Twitter and Reddit.")
 
-	issue, err := ctx.GitHub.GetIssue(repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseIssueTitle(ctx.Version))
+	issue, err := a.GitHub.GetIssue(ctx, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseIssueTitle(a.Version))
 	if err != nil {
 		return err
 	}
 	if issue == nil {
-		return fmt.Errorf("⚠️ issue '%s' not found in https://github.com/%s/%s/issues (%w)", repos.Kubo.ReleaseIssueTitle(ctx.Version), repos.Kubo.Owner, repos.Kubo.Repo, ErrFailure)
+		return fmt.Errorf("⚠️ issue '%s' not found in %s/%s/%s/issues (%w)", repos.Kubo.ReleaseIssueTitle(a.Version), util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, ErrFailure)
 	}
 
-	comment, err := ctx.GitHub.GetIssueComment(repos.Kubo.Owner, repos.Kubo.Repo, issue.GetNumber(), ctx.getReleaseIssueComment())
+	comment, err := a.GitHub.GetIssueComment(ctx, repos.Kubo.Owner, repos.Kubo.Repo, issue.GetNumber(), a.getReleaseIssueComment(ctx))
 	if err != nil {
 		return err
 	}
 	if comment == nil {
-		return fmt.Errorf("⚠️ comment '%s' not found in %s (%w)", ctx.getReleaseIssueComment(), issue.GetHTMLURL(), ErrIncomplete)
+		return fmt.Errorf("⚠️ comment '%s' not found in %s (%w)", a.getReleaseIssueComment(ctx), issue.GetHTMLURL(), ErrIncomplete)
 	}
 
-	if ctx.Matrix == nil {
-		log.Warn("Skipping Matrix checks because the client was not configured.")
+	if a.Matrix == nil {
+		util.Logger(ctx).Warn("Skipping Matrix checks because the client was not configured.")
 	} else {
-		messages, err := ctx.Matrix.GetLatestMessagesBy("#ipfs-chatter:ipfs.io", "@ipfsbot:matrix.org", 10)
+		messages, err := a.Matrix.GetLatestMessagesBy(ctx, "#ipfs-chatter:ipfs.io", "@ipfsbot:matrix.org", 10)
 		if err != nil {
 			return err
 		}
@@ -105,23 +112,23 @@ func (ctx Promote) Check() error {
 		var found bool
 		for _, message := range messages {
 			body, ok := message.Body()
-			if ok && strings.Contains(body, ctx.getDiscoursePostTitle()) {
+			if ok && strings.Contains(body, a.getDiscoursePostTitle()) {
 				found = true
 				break
 			}
 		}
 		if !found {
-			return fmt.Errorf("⚠️ post '%s' not found in https://matrix.to/#/#ipfs-chatter:ipfs.io (%w)", ctx.getDiscoursePostTitle(), ErrIncomplete)
+			return fmt.Errorf("⚠️ post '%s' not found in https://matrix.to/#/#ipfs-chatter:ipfs.io (%w)", a.getDiscoursePostTitle(), ErrIncomplete)
 		}
 	}
 
-	if !ctx.Version.IsPrerelease() {
-		release, err := ctx.GitHub.GetRelease(repos.Kubo.Owner, repos.Kubo.Repo, ctx.Version.String())
+	if !a.Version.IsPrerelease() {
+		release, err := a.GitHub.GetRelease(ctx, repos.Kubo.Owner, repos.Kubo.Repo, a.Version.String())
 		if err != nil {
 			return err
 		}
 		if release == nil {
-			return fmt.Errorf("⚠️ release '%s' not found in https://github.com/%s/%s/releases (%w)", ctx.Version, repos.Kubo.Owner, repos.Kubo.Repo, ErrFailure)
+			return fmt.Errorf("⚠️ release '%s' not found in %s/%s/%s/releases (%w)", a.Version, util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, ErrFailure)
 		}
 		if !strings.Contains(release.GetBody(), "- 💬 [Discuss]") {
 			return fmt.Errorf("⚠️ %s does not contain a discuss link (%w)", release.GetHTMLURL(), ErrIncomplete)
@@ -131,20 +138,20 @@ func (ctx Promote) Check() error {
 	return nil
 }
 
-func (ctx Promote) Run() error {
-	log.Info("I'm going to promote the release on GitHub, Discourse, Twitter and Reddit.")
+func (a Promote) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to promote the release on GitHub, Discourse, Twitter and Reddit.")
 
-	url := repos.Kubo.ReleaseURL(ctx.Version)
+	url := repos.Kubo.ReleaseURL(a.Version)
 
-	issue, err := ctx.GitHub.GetIssue(repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseIssueTitle(ctx.Version))
+	issue, err := a.GitHub.GetIssue(ctx, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseIssueTitle(a.Version))
 	if err != nil {
 		return err
 	}
 	if issue == nil {
-		return fmt.Errorf("🚨 issue '%s' not found in https://github.com/%s/%s/issues", repos.Kubo.ReleaseIssueTitle(ctx.Version), repos.Kubo.Owner, repos.Kubo.Repo)
+		return fmt.Errorf("🚨 issue '%s' not found in %s/%s/%s/issues", repos.Kubo.ReleaseIssueTitle(a.Version), util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo)
 	}
 
-	_, err = ctx.GitHub.GetOrCreateIssueComment(repos.Kubo.Owner, repos.Kubo.Repo, issue.GetNumber(), ctx.getReleaseIssueComment())
+	_, err = a.GitHub.GetOrCreateIssueComment(ctx, repos.Kubo.Owner, repos.Kubo.Repo, issue.GetNumber(), a.getReleaseIssueComment(ctx))
 	if err != nil {
 		return err
 	}
@@ -159,25 +166,25 @@ Body: %s
 
 Remember to pin the topic globally!
 
-Please approve once the post is up.`, ctx.getDiscoursePostTitle(), ctx.getDiscoursePostBody())
-	if !util.Confirm(prompt) {
+Please approve once the post is up.`, a.getDiscoursePostTitle(), a.getDiscoursePostBody())
+	if !Confirm(ctx, a.Prompter, Prompt{ID: "promote/discourse-post", Message: prompt}) {
 		return fmt.Errorf("🚨 creation of discourse post was not confirmed correctly")
 	}
 
-	if !ctx.Version.IsPrerelease() {
+	if !a.Version.IsPrerelease() {
 		prompt := fmt.Sprintf(`Go to %s and add the link to the IPFS Discourse post to the top of the release notes.
 
 Use the following template:
 - [💬 Discuss](https://discuss.ipfs.tech/t/kubo-%s-is-out/XXXX)
 
-Please approve once the post is linked.`, url, strings.ReplaceAll(ctx.Version.String(), ".", "-"))
+Please approve once the post is linked.`, url, strings.ReplaceAll(a.Version.String(), ".", "-"))
 
-		if !util.Confirm(prompt) {
+		if !Confirm(ctx, a.Prompter, Prompt{ID: "promote/discourse-link", Message: prompt}) {
 			return fmt.Errorf("🚨 %s does not contain a discuss link", url)
 		}
 	}
 
-	if !ctx.Version.IsPrerelease() && !ctx.Version.IsPatch() {
+	if !a.Version.IsPrerelease() && !a.Version.IsPatch() {
 		prompt := fmt.Sprintf(`Reddit supports only OAuth2 authentication.
 
 Please go to https://www.reddit.com/r/ipfs/new/ and create a new "Link" post with the following content:
@@ -185,16 +192,16 @@ Please go to https://www.reddit.com/r/ipfs/new/ and create a new "Link" post wit
 Url: %s
 
 Please approve once the post is up.`, url)
-		if !util.Confirm(prompt) {
+		if !Confirm(ctx, a.Prompter, Prompt{ID: "promote/reddit-post", Message: prompt}) {
 			return fmt.Errorf("🚨 creation of reddit post was not confirmed correctly")
 		}
 
-		file, err := ctx.GitHub.GetFile(repos.Kubo.Owner, repos.Kubo.Repo, "docs/changelogs/"+ctx.Version.MajorMinor()+".md", "release")
+		file, err := a.GitHub.GetFile(ctx, repos.Kubo.Owner, repos.Kubo.Repo, "docs/changelogs/"+a.Version.MajorMinor()+".md", "release")
 		if err != nil {
 			return err
 		}
 		if file == nil {
-			return fmt.Errorf("🚨 https://github.com/%s/%s/blob/release/docs/changelogs/%s.md not found", repos.Kubo.Owner, repos.Kubo.Repo, ctx.Version.MajorMinor())
+			return fmt.Errorf("🚨 %s/%s/%s/blob/release/docs/changelogs/%s.md not found", util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, a.Version.MajorMinor())
 		}
 
 		content, err := base64.StdEncoding.DecodeString(*file.Content)
@@ -217,11 +224,15 @@ What's happening?: #Kubo %s was just released!
 %s
 %s
 
-Please approve once the message is up.`, ctx.Version, strings.Join(highlights, "\n"), url)
-		if !util.Confirm(prompt) {
+Please approve once the message is up.`, a.Version, strings.Join(highlights, "\n"), url)
+		if !Confirm(ctx, a.Prompter, Prompt{ID: "promote/tweet", Message: prompt}) {
 			return fmt.Errorf("🚨 creation of twitter post was not confirmed correctly")
 		}
 	}
 
 	return nil
 }
+
+func (a Promote) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
+}
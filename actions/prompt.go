@@ -0,0 +1,191 @@
+package actions
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	gh "github.com/google/go-github/v48/github"
+	"github.com/ipfs/kuboreleaser/events"
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/util"
+	log "github.com/sirupsen/logrus"
+)
+
+type Prompt struct {
+	// ID identifies the prompt across releases so that the answers can be scripted, e.g. tag/push
+	ID      string
+	Message string
+	// PR is set when the prompt asks for the PR to be merged
+	PR *gh.PullRequest
+}
+
+// PRPrompt asks for the PR to be merged
+func PRPrompt(id string, pr *gh.PullRequest) Prompt {
+	return Prompt{
+		ID: id,
+		Message: fmt.Sprintf(`Go to %s, ensure the CI checks pass, and merge the PR.
+
+Please approve once the PR is merged.`, pr.GetHTMLURL()),
+		PR: pr,
+	}
+}
+
+type Prompter interface {
+	// Confirm reports whether the prompt was approved
+	Confirm(ctx context.Context, prompt Prompt) bool
+}
+
+// Confirm asks the prompter, or the user if there is none, and reports the answer to the event stream
+func Confirm(ctx context.Context, prompter Prompter, prompt Prompt) bool {
+	if prompter == nil {
+		prompter = TTYPrompter{}
+	}
+	approved := prompter.Confirm(ctx, prompt)
+	events.Emit(ctx, events.Event{
+		Phase:    events.PhaseConfirm,
+		Prompt:   prompt.ID,
+		Approved: &approved,
+		URLs:     events.URLs(prompt.Message),
+	})
+	return approved
+}
+
+// prompts are serialized so that actions running concurrently do not read each other's answers
+var promptMu sync.Mutex
+
+// TTYPrompter asks the user to type 'yes'
+type TTYPrompter struct{}
+
+func (p TTYPrompter) Confirm(ctx context.Context, prompt Prompt) bool {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	var confirmation string
+	fmt.Fprintf(util.Output, `👉👉👉 %s
+
+Only 'yes' will be accepted to approve.
+
+Enter a value: `, prompt.Message)
+	fmt.Scanln(&confirmation)
+	return confirmation == "yes"
+}
+
+// AutoApprover approves every prompt, which is only useful for rehearsals
+type AutoApprover struct{}
+
+func (p AutoApprover) Confirm(ctx context.Context, prompt Prompt) bool {
+	util.Logger(ctx).WithField("prompt", prompt.ID).Info("👍 Automatically approved: ", prompt.Message)
+	return true
+}
+
+// AnswerPrompter looks the answers up by the prompt ID.
+// An answer set in the environment (e.g. KUBORELEASER_ANSWER_TAG_PUSH=yes for tag/push) takes precedence over Answers.
+// Prompts without an answer are passed to Fallback, or rejected if there is none.
+type AnswerPrompter struct {
+	Answers  map[string]string
+	Fallback Prompter
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// AnswerEnv returns the name of the environment variable that answers the prompt
+func AnswerEnv(id string) string {
+	return "KUBORELEASER_ANSWER_" + nonAlphanumeric.ReplaceAllString(strings.ToUpper(id), "_")
+}
+
+// ReadAnswers parses a file with one '<prompt id>=<answer>' per line, lines starting with '#' are ignored
+func ReadAnswers(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	answers := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, answer, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("🚨 line %d of %s is not in the '<prompt id>=<answer>' format", n, path)
+		}
+		answers[strings.TrimSpace(id)] = strings.TrimSpace(answer)
+	}
+	return answers, scanner.Err()
+}
+
+func (p AnswerPrompter) Confirm(ctx context.Context, prompt Prompt) bool {
+	logger := util.Logger(ctx).WithField("prompt", prompt.ID)
+
+	answer, ok := os.LookupEnv(AnswerEnv(prompt.ID))
+	if !ok {
+		answer, ok = p.Answers[prompt.ID]
+	}
+	if ok {
+		logger.Info("Answered '", answer, "' to the prompt")
+		return answer == "yes"
+	}
+
+	if p.Fallback != nil {
+		return p.Fallback.Confirm(ctx, prompt)
+	}
+	logger.Error("🚨 There is no answer to: ", prompt.Message)
+	logger.Error("Set ", AnswerEnv(prompt.ID), "=yes or add '", prompt.ID, "=yes' to the answers file to approve it")
+	return false
+}
+
+// WaitPrompter approves the prompts about PRs once the PRs get merged.
+// The other prompts are passed to Fallback.
+type WaitPrompter struct {
+	GitHub   github.API
+	Poll     Poll
+	Fallback Prompter
+}
+
+func (p WaitPrompter) Confirm(ctx context.Context, prompt Prompt) bool {
+	if prompt.PR == nil {
+		return p.Fallback.Confirm(ctx, prompt)
+	}
+
+	logger := util.Logger(ctx).WithFields(log.Fields{
+		"prompt": prompt.ID,
+		"url":    prompt.PR.GetHTMLURL(),
+	})
+	if prompt.PR.GetNumber() == 0 {
+		logger.Info("Not waiting for the PR to be merged because it was not actually created")
+		return true
+	}
+
+	owner := prompt.PR.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := prompt.PR.GetBase().GetRepo().GetName()
+	head := prompt.PR.GetHead().GetRef()
+	for attempt := 0; ; attempt++ {
+		pr, err := p.GitHub.GetPR(ctx, owner, repo, head)
+		if err != nil {
+			logger.Warn("Failed to get the PR: ", err)
+		} else if pr.GetMerged() {
+			logger.Info("The PR is merged")
+			return true
+		} else if pr.GetState() == "closed" {
+			logger.Error("🚨 The PR was closed without being merged")
+			return false
+		}
+
+		duration := p.Poll.Delay(attempt + 1)
+		logger.Info("Waiting for someone to merge the PR, checking again in ", duration, "...")
+		select {
+		case <-time.After(duration):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -1,55 +1,63 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ipfs/kuboreleaser/git"
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type PublishToDistributions struct {
-	Git     *git.Client
-	GitHub  *github.Client
-	Version *util.Version
+	Git      *git.Client
+	GitHub   github.API
+	Version  *util.Version
+	Prompter Prompter
 }
 
-func (ctx PublishToDistributions) Check() error {
-	log.Info("I'm going to check if the PR that publishes the release to distributions exists and if it's merged already.")
+func (a PublishToDistributions) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the PR that publishes the release to distributions exists and if it's merged already.")
 
-	err := CheckPR(ctx.GitHub, repos.Distributions.Owner, repos.Distributions.Repo, repos.Distributions.KuboBranch(ctx.Version), true)
+	err := CheckPR(ctx, a.GitHub, repos.Distributions.Owner, repos.Distributions.Repo, repos.Distributions.KuboBranch(a.Version), true)
 	if err != nil {
 		return err
 	}
 
-	return CheckBranch(ctx.GitHub, repos.Distributions.Owner, repos.Distributions.Repo, repos.Distributions.DefaultBranch)
+	return CheckBranch(ctx, a.GitHub, repos.Distributions.Owner, repos.Distributions.Repo, repos.Distributions.DefaultBranch, repos.Distributions.DefaultBranch)
 }
 
-func (ctx PublishToDistributions) Run() error {
-	log.Info("I'm going to create a PR that publishes the release to distributions and ask you to merge it for me.")
+func (a PublishToDistributions) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a PR that publishes the release to distributions and ask you to merge it for me.")
 
-	branch := repos.Distributions.KuboBranch(ctx.Version)
-	title := fmt.Sprintf("Publish Kubo: %s", ctx.Version)
-	body := fmt.Sprintf("This PR initiates publishing of Kubo %s", ctx.Version)
+	branch := repos.Distributions.KuboBranch(a.Version)
+	title := fmt.Sprintf("Publish Kubo: %s", a.Version)
+	body := withReleaseIssueRef(ctx, a.GitHub, a.Version, fmt.Sprintf("This PR initiates publishing of Kubo %s", a.Version))
 
-	b, err := ctx.GitHub.GetOrCreateBranch(repos.Distributions.Owner, repos.Distributions.Repo, branch, repos.Distributions.DefaultBranch)
+	b, err := a.GitHub.GetOrCreateBranch(ctx, repos.Distributions.Owner, repos.Distributions.Repo, branch, repos.Distributions.DefaultBranch)
 	if err != nil {
 		return err
 	}
 
-	err = ctx.Git.RunAndPush(repos.Distributions.Owner, repos.Distributions.Repo, branch, b.GetCommit().GetSHA(), "chore: add Kubo release", util.Command{Name: "./dist.sh", Args: []string{"add-version", "kubo", ctx.Version.Version}})
+	err = a.Git.RunAndPush(ctx, repos.Distributions.Owner, repos.Distributions.Repo, branch, b.GetCommit().GetSHA(), "chore: add Kubo release", util.Command{Name: "./dist.sh", Args: []string{"add-version", "kubo", a.Version.Version}})
 	if err != nil {
 		return err
 	}
 
-	pr, err := ctx.GitHub.GetOrCreatePR(repos.Distributions.Owner, repos.Distributions.Repo, branch, repos.Distributions.DefaultBranch, title, body, false)
+	pr, err := a.GitHub.GetOrCreatePR(ctx, repos.Distributions.Owner, repos.Distributions.Repo, branch, repos.Distributions.DefaultBranch, title, body, false)
 	if err != nil {
 		return err
 	}
-	if !util.ConfirmPR(pr) {
+	assignReviewers(ctx, a.GitHub, pr)
+	enableAutoMerge(ctx, a.GitHub, pr, github.MergeMethodSquash)
+	if !Confirm(ctx, a.Prompter, PRPrompt("publish-to-distributions/merge-pr", pr)) {
 		return fmt.Errorf("🚨 %s not merged", pr.GetHTMLURL())
 	}
 	return nil
 }
+
+func (a PublishToDistributions) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
+}
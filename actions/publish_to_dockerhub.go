@@ -1,27 +1,46 @@
 package actions
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type PublishToDockerHub struct {
-	GitHub  *github.Client
+	GitHub  github.API
 	Version *util.Version
+	Journal *journal.Entry
 }
 
-func (ctx PublishToDockerHub) Check() error {
-	log.Info("I'm going to check if the workflow that publishes the Docker image to Docker Hub has run already.")
+func (a PublishToDockerHub) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the workflow that publishes the Docker image to Docker Hub has run already.")
 
-	return CheckWorkflowRun(ctx.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, ctx.Version.Version, repos.Kubo.DockerHubWorkflowName, repos.Kubo.DockerHubWorkflowJobName, fmt.Sprintf("ipfs/kubo:%s", ctx.Version))
+	return CheckWorkflowRun(ctx, a.GitHub, a.Journal, repos.Kubo.Owner, repos.Kubo.Repo, a.Version.Version, repos.Kubo.DockerHubWorkflowName, repos.Kubo.DockerHubWorkflowJobName, "", fmt.Sprintf("ipfs/kubo:%s", a.Version))
 }
 
-func (ctx PublishToDockerHub) Run() error {
-	log.Info("I'm going to create a workflow run that publishes the Docker image to Docker Hub.")
+func (a PublishToDockerHub) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a workflow run that publishes the Docker image to Docker Hub.")
 
-	return ctx.GitHub.CreateWorkflowRun(repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DockerHubWorkflowName, ctx.Version.Version)
+	_, err := a.GitHub.CreateWorkflowRun(ctx, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DockerHubWorkflowName, a.Version.Version)
+	return err
+}
+
+func (a PublishToDockerHub) Poll() Poll {
+	poll := WorkflowPoll
+	// the multi-arch image build takes considerably longer than the other workflows
+	poll.Deadline = 2 * time.Hour
+	return poll
+}
+
+func (a PublishToDockerHub) Follow(ctx context.Context, tail *WorkflowTail) error {
+	return tail.Follow(ctx, a.GitHub, a.Journal, repos.Kubo.Owner, repos.Kubo.Repo, a.Version.Version, repos.Kubo.DockerHubWorkflowName)
+}
+
+func (a PublishToDockerHub) Retry(ctx context.Context, retries int) (bool, error) {
+	return RetryWorkflowRun(ctx, a.GitHub, a.Journal, repos.Kubo.Owner, repos.Kubo.Repo, a.Version.Version, repos.Kubo.DockerHubWorkflowName, retries)
 }
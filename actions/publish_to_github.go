@@ -1,48 +1,50 @@
 package actions
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"strings"
 
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type PublishToGitHub struct {
-	GitHub  *github.Client
+	GitHub  github.API
 	Version *util.Version
+	Journal *journal.Entry
 }
 
-func (ctx PublishToGitHub) Check() error {
-	log.Info("I'm going to check if the release has been created in GitHub and if the workflow that syncs the release assets has run already.")
+func (a PublishToGitHub) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the release has been created in GitHub and if the workflow that syncs the release assets has run already.")
 
-	release, err := ctx.GitHub.GetRelease(repos.Kubo.Owner, repos.Kubo.Repo, ctx.Version.String())
+	release, err := a.GitHub.GetRelease(ctx, repos.Kubo.Owner, repos.Kubo.Repo, a.Version.String())
 	if err != nil {
 		return err
 	}
 	if release == nil {
-		return fmt.Errorf("⚠️ release '%s' not found in https://github.com/%s/%s/releases (%w)", ctx.Version.String(), repos.Kubo.Owner, repos.Kubo.Repo, ErrIncomplete)
+		return fmt.Errorf("⚠️ release '%s' not found in %s/%s/%s/releases (%w)", a.Version.String(), util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, ErrIncomplete)
 	}
 
-	return CheckWorkflowRun(ctx.GitHub, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DefaultBranch, repos.Kubo.SyncReleaseAssetsWorkflowName, repos.Kubo.SyncReleaseAssetsWorkflowJobName, ctx.Version.String())
+	return CheckWorkflowRun(ctx, a.GitHub, a.Journal, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DefaultBranch, repos.Kubo.SyncReleaseAssetsWorkflowName, repos.Kubo.SyncReleaseAssetsWorkflowJobName, "", a.Version.String())
 }
 
-func (ctx PublishToGitHub) Run() error {
-	log.Info("I'm going to create a release in GitHub and a workflow run that syncs the release assets.")
+func (a PublishToGitHub) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a release in GitHub and a workflow run that syncs the release assets.")
 
 	var body string
-	if ctx.Version.IsPrerelease() {
-		body = fmt.Sprintf("Changelog: [docs/changelogs/%s.md](https://github.com/ipfs/kubo/blob/release-%s/docs/changelogs/%s.md)", ctx.Version.MajorMinor(), ctx.Version.MajorMinorPatch(), ctx.Version.MajorMinor())
+	if a.Version.IsPrerelease() {
+		body = fmt.Sprintf("Changelog: [docs/changelogs/%s.md](%s)", a.Version.MajorMinor(), util.GitHubURL("%s/%s/blob/release-%s/docs/changelogs/%s.md", repos.Kubo.Owner, repos.Kubo.Repo, a.Version.MajorMinorPatch(), a.Version.MajorMinor()))
 	} else {
-		file, err := ctx.GitHub.GetFile(repos.Kubo.Owner, repos.Kubo.Repo, fmt.Sprintf("docs/changelogs/%s.md", ctx.Version.MajorMinor()), repos.Kubo.ReleaseBranch)
+		file, err := a.GitHub.GetFile(ctx, repos.Kubo.Owner, repos.Kubo.Repo, fmt.Sprintf("docs/changelogs/%s.md", a.Version.MajorMinor()), repos.Kubo.ReleaseBranch)
 		if err != nil {
 			return err
 		}
 		if file == nil {
-			return fmt.Errorf("🚨 https://github.com/%s/%s/blob/%s/docs/changelogs/%s.md not found", repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseBranch, ctx.Version.MajorMinor())
+			return fmt.Errorf("🚨 %s/%s/%s/blob/%s/docs/changelogs/%s.md not found", util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.ReleaseBranch, a.Version.MajorMinor())
 		}
 
 		content, err := base64.StdEncoding.DecodeString(*file.Content)
@@ -52,7 +54,7 @@ func (ctx PublishToGitHub) Run() error {
 
 		body = string(content)
 
-		header := fmt.Sprintf("## %s\n", ctx.Version.MajorMinorPatch())
+		header := fmt.Sprintf("## %s\n", a.Version.MajorMinorPatch())
 
 		index := strings.Index(body, header)
 		if index != -1 {
@@ -67,7 +69,7 @@ func (ctx PublishToGitHub) Run() error {
 		}
 	}
 
-	latestRelease, err := ctx.GitHub.GetLatestRelease(repos.Kubo.Owner, repos.Kubo.Repo)
+	latestRelease, err := a.GitHub.GetLatestRelease(ctx, repos.Kubo.Owner, repos.Kubo.Repo)
 	if err != nil {
 		return err
 	}
@@ -77,10 +79,27 @@ func (ctx PublishToGitHub) Run() error {
 		return err
 	}
 
-	_, err = ctx.GitHub.GetOrCreateRelease(repos.Kubo.Owner, repos.Kubo.Repo, ctx.Version.String(), ctx.Version.String(), body, ctx.Version.IsPrerelease(), ctx.Version.Compare(latestVersion) >= 0)
+	_, err = a.GitHub.GetOrCreateRelease(ctx, repos.Kubo.Owner, repos.Kubo.Repo, a.Version.String(), a.Version.String(), body, a.Version.IsPrerelease(), a.Version.Compare(latestVersion) >= 0)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GitHub.CreateWorkflowRun(repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.SyncReleaseAssetsWorkflowName, repos.Kubo.DefaultBranch)
+	_, err = a.GitHub.CreateWorkflowRun(ctx, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.SyncReleaseAssetsWorkflowName, repos.Kubo.DefaultBranch)
+	return err
+}
+
+func (a PublishToGitHub) Poll() Poll {
+	return WorkflowPoll
+}
+
+func (a PublishToGitHub) Follow(ctx context.Context, tail *WorkflowTail) error {
+	return tail.Follow(ctx, a.GitHub, a.Journal, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DefaultBranch, repos.Kubo.SyncReleaseAssetsWorkflowName)
+}
+
+func (a PublishToGitHub) Retry(ctx context.Context, retries int) (bool, error) {
+	return RetryWorkflowRun(ctx, a.GitHub, a.Journal, repos.Kubo.Owner, repos.Kubo.Repo, repos.Kubo.DefaultBranch, repos.Kubo.SyncReleaseAssetsWorkflowName, retries)
+}
+
+func (a PublishToGitHub) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
 }
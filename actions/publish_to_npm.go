@@ -1,27 +1,42 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type PublishToNPM struct {
-	GitHub  *github.Client
+	GitHub  github.API
 	Version *util.Version
+	Journal *journal.Entry
 }
 
-func (ctx PublishToNPM) Check() error {
-	log.Info("I'm going to check if the workflow that publishes the NPM package has run already.")
+func (a PublishToNPM) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the workflow that publishes the NPM package has run already.")
 
-	return CheckWorkflowRun(ctx.GitHub, repos.NPMKubo.Owner, repos.NPMKubo.Repo, repos.NPMKubo.DefaultBranch, repos.NPMKubo.WorkflowName, repos.NPMKubo.WorkflowJobName, fmt.Sprintf(" %s\n", ctx.Version.String()[1:]))
+	return CheckWorkflowRun(ctx, a.GitHub, a.Journal, repos.NPMKubo.Owner, repos.NPMKubo.Repo, repos.NPMKubo.DefaultBranch, repos.NPMKubo.WorkflowName, repos.NPMKubo.WorkflowJobName, "", fmt.Sprintf(" %s\n", a.Version.String()[1:]))
 }
 
-func (ctx PublishToNPM) Run() error {
-	log.Info("I'm going to create a workflow run that publishes the NPM package.")
+func (a PublishToNPM) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a workflow run that publishes the NPM package.")
 
-	return ctx.GitHub.CreateWorkflowRun(repos.NPMKubo.Owner, repos.NPMKubo.Repo, repos.NPMKubo.WorkflowName, repos.NPMKubo.DefaultBranch)
+	_, err := a.GitHub.CreateWorkflowRun(ctx, repos.NPMKubo.Owner, repos.NPMKubo.Repo, repos.NPMKubo.WorkflowName, repos.NPMKubo.DefaultBranch)
+	return err
+}
+
+func (a PublishToNPM) Poll() Poll {
+	return WorkflowPoll
+}
+
+func (a PublishToNPM) Follow(ctx context.Context, tail *WorkflowTail) error {
+	return tail.Follow(ctx, a.GitHub, a.Journal, repos.NPMKubo.Owner, repos.NPMKubo.Repo, repos.NPMKubo.DefaultBranch, repos.NPMKubo.WorkflowName)
+}
+
+func (a PublishToNPM) Retry(ctx context.Context, retries int) (bool, error) {
+	return RetryWorkflowRun(ctx, a.GitHub, a.Journal, repos.NPMKubo.Owner, repos.NPMKubo.Repo, repos.NPMKubo.DefaultBranch, repos.NPMKubo.WorkflowName, retries)
 }
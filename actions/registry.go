@@ -0,0 +1,188 @@
+package actions
+
+import (
+	"github.com/ipfs/kuboreleaser/git"
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
+	"github.com/ipfs/kuboreleaser/matrix"
+	"github.com/ipfs/kuboreleaser/util"
+	"github.com/urfave/cli/v2"
+)
+
+type Requirement int
+
+const (
+	RequiresGit Requirement = 1 << iota
+	RequiresGitHub
+	RequiresMatrix
+)
+
+// Clients holds the clients an action is constructed with, only the ones it requires are set
+type Clients struct {
+	Git    *git.Client
+	GitHub github.API
+	Matrix *matrix.Client
+	// Prompter is always set, the actions which never prompt just ignore it
+	Prompter Prompter
+	// Journal is the journal entry of the action, it is nil outside of a release
+	Journal *journal.Entry
+}
+
+type Registration struct {
+	// Node places the action in the release graph
+	Node
+	Usage    string
+	Requires Requirement
+	Flags    []cli.Flag
+	// New constructs the action, flags gives access to the values of Flags
+	New func(clients Clients, version *util.Version, flags *cli.Context) IAction
+}
+
+func (r Registration) Has(requirement Requirement) bool {
+	return r.Requires&requirement != 0
+}
+
+func isPrerelease(version *util.Version) bool {
+	return version.IsPrerelease()
+}
+
+// Registry lists the release actions in the order in which they are declared in the release graph
+var Registry = []Registration{
+	{
+		Node:     Node{Name: "prepare-branch"},
+		Usage:    "Prepare a branch for the release",
+		Requires: RequiresGit | RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &PrepareBranch{Git: clients.Git, GitHub: clients.GitHub, Version: version, Prompter: clients.Prompter}
+		},
+	},
+	{
+		Node:     Node{Name: "tag", After: []string{"prepare-branch"}},
+		Usage:    "Tag the release",
+		Requires: RequiresGit | RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &Tag{Git: clients.Git, GitHub: clients.GitHub, Version: version, Prompter: clients.Prompter}
+		},
+	},
+	{
+		Node:     Node{Name: "publish-to-dockerhub", After: []string{"tag"}},
+		Usage:    "Publish the release to DockerHub",
+		Requires: RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &PublishToDockerHub{GitHub: clients.GitHub, Version: version, Journal: clients.Journal}
+		},
+	},
+	{
+		Node:     Node{Name: "publish-to-distributions", After: []string{"tag"}},
+		Usage:    "Publish the release to distributions",
+		Requires: RequiresGit | RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &PublishToDistributions{Git: clients.Git, GitHub: clients.GitHub, Version: version, Prompter: clients.Prompter}
+		},
+	},
+	{
+		Node:     Node{Name: "publish-to-npm", After: []string{"publish-to-distributions"}},
+		Usage:    "Publish the release to npm",
+		Requires: RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &PublishToNPM{GitHub: clients.GitHub, Version: version, Journal: clients.Journal}
+		},
+	},
+	{
+		Node:     Node{Name: "publish-to-github", After: []string{"publish-to-distributions"}},
+		Usage:    "Publish the release to GitHub",
+		Requires: RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &PublishToGitHub{GitHub: clients.GitHub, Version: version, Journal: clients.Journal}
+		},
+	},
+	{
+		Node:     Node{Name: "test-ipfs-companion", After: []string{"publish-to-dockerhub"}},
+		Usage:    "Test the release with ipfs-companion",
+		Requires: RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &TestIPFSCompanion{GitHub: clients.GitHub, Version: version, Journal: clients.Journal}
+		},
+	},
+	{
+		Node:     Node{Name: "update-ipfs-desktop", After: []string{"publish-to-npm"}},
+		Usage:    "Update the release in ipfs-desktop",
+		Requires: RequiresGit | RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &UpdateIPFSDesktop{Git: clients.Git, GitHub: clients.GitHub, Version: version}
+		},
+	},
+	{
+		Node:     Node{Name: "update-ipfs-docs", After: []string{"publish-to-github"}, Skip: isPrerelease},
+		Usage:    "Update the release in ipfs-docs",
+		Requires: RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &UpdateIPFSDocs{GitHub: clients.GitHub, Version: version, Journal: clients.Journal}
+		},
+	},
+	{
+		Node:     Node{Name: "update-ipfs-blog", After: []string{"publish-to-github"}, Skip: isPrerelease},
+		Usage:    "Update the release in ipfs-blog",
+		Requires: RequiresGit | RequiresGitHub,
+		Flags: []cli.Flag{
+			&cli.TimestampFlag{
+				Name:    "date",
+				Aliases: []string{"d"},
+				Usage:   "Date of the release",
+				Layout:  "2006-01-02",
+			},
+		},
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &UpdateIPFSBlog{Git: clients.Git, GitHub: clients.GitHub, Version: version, Date: flags.Timestamp("date"), Prompter: clients.Prompter}
+		},
+	},
+	{
+		Node:     Node{Name: "promote", After: []string{"publish-to-dockerhub", "publish-to-npm", "publish-to-github"}},
+		Usage:    "Promote the release",
+		Requires: RequiresGitHub | RequiresMatrix,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "skip-matrix",
+				Usage: "Do not use Matrix client",
+				Value: util.GetenvBool("NO_MATRIX"),
+			},
+		},
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &Promote{GitHub: clients.GitHub, Matrix: clients.Matrix, Version: version, Prompter: clients.Prompter}
+		},
+	},
+	{
+		Node:     Node{Name: "merge-branch", After: []string{"tag"}, Skip: isPrerelease},
+		Usage:    "Merge the release branch into master",
+		Requires: RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &MergeBranch{GitHub: clients.GitHub, Version: version, Prompter: clients.Prompter}
+		},
+	},
+	{
+		Node:     Node{Name: "prepare-next", After: []string{"merge-branch"}, Skip: func(v *util.Version) bool { return v.IsPrerelease() || v.IsPatch() }},
+		Usage:    "Prepare the next release",
+		Requires: RequiresGit | RequiresGitHub,
+		New: func(clients Clients, version *util.Version, flags *cli.Context) IAction {
+			return &PrepareNext{Git: clients.Git, GitHub: clients.GitHub, Version: version, Prompter: clients.Prompter}
+		},
+	},
+}
+
+func Lookup(name string) (Registration, bool) {
+	for _, r := range Registry {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Registration{}, false
+}
+
+// ReleaseGraph returns the dependencies between the registered actions
+func ReleaseGraph() Graph {
+	graph := make(Graph, len(Registry))
+	for i, r := range Registry {
+		graph[i] = r.Node
+	}
+	return graph
+}
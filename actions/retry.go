@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
+	"github.com/ipfs/kuboreleaser/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Retrier can be implemented by actions which can retry what failed, e.g. by rerunning the failed jobs of a workflow run
+type Retrier interface {
+	// Retry reports whether it retried, it returns an ErrFailure once the retries are exhausted
+	Retry(ctx context.Context, retries int) (bool, error)
+}
+
+// RetryWorkflowRun reruns the failed jobs of the run that the action dispatched, see findWorkflowRun, unless it was retried that many times already.
+// The attempts are counted by GitHub, so the retries are not reset when the release is resumed.
+func RetryWorkflowRun(ctx context.Context, client github.API, entry *journal.Entry, owner, repo, branch, file string, retries int) (bool, error) {
+	run, err := findWorkflowRun(ctx, client, entry, owner, repo, branch, file)
+	if err != nil {
+		return false, err
+	}
+	if run.GetStatus() != "completed" || run.GetConclusion() == "success" {
+		return false, nil
+	}
+
+	attempt := run.GetRunAttempt()
+	if attempt > retries {
+		var urls []string
+		for i := 1; i <= attempt; i++ {
+			urls = append(urls, fmt.Sprintf("%s/attempts/%d", run.GetHTMLURL(), i))
+		}
+		return false, fmt.Errorf("🚨 %s failed %d times, see %s (%w)", file, attempt, strings.Join(urls, ", "), ErrFailure)
+	}
+
+	util.Logger(ctx).WithFields(log.Fields{
+		"url":     fmt.Sprintf("%s/attempts/%d", run.GetHTMLURL(), attempt),
+		"attempt": attempt,
+	}).Warn("⚠️ ", file, " ", strings.ReplaceAll(run.GetConclusion(), "_", " "), ", rerunning its failed jobs (retry ", attempt, " of ", retries, ")...")
+	err = client.RerunFailedJobs(ctx, owner, repo, run.GetID())
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,207 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
+	"github.com/ipfs/kuboreleaser/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Reverter can be implemented by actions which are able to undo what their Run created
+type Reverter interface {
+	// Revert is given the artifacts which the journal says the action created
+	Revert(ctx context.Context, artifacts []journal.Artifact) error
+}
+
+// RevertError is returned when only some of the artifacts could be reverted
+type RevertError struct {
+	Reverted  []journal.Artifact
+	Remaining []journal.Artifact
+	errs      []error
+}
+
+func (e *RevertError) Error() string {
+	lines := []string{fmt.Sprintf("🚨 %d of the artifacts were not reverted:", len(e.Remaining))}
+	for i, a := range e.Remaining {
+		lines = append(lines, fmt.Sprintf("- %s %s: %s", a.Kind, a.URL, e.errs[i]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *RevertError) Unwrap() []error {
+	return e.errs
+}
+
+// dependsOn reports whether the artifact can only be reverted after the other one, i.e. the head branch of a PR and the tag of a release
+func dependsOn(a, other journal.Artifact) bool {
+	if a.Owner != other.Owner || a.Repo != other.Repo || a.Name != other.Name {
+		return false
+	}
+	return a.Kind == journal.ArtifactBranch && other.Kind == journal.ArtifactPR || a.Kind == journal.ArtifactTag && other.Kind == journal.ArtifactRelease
+}
+
+// revert undoes the artifacts in the reverse order of their creation.
+// Before anything is deleted, the remote state is checked to make sure that nobody built on top of it in the meantime.
+// An artifact which cannot be reverted does not stop the others, only the ones which depend on it are left in place too, see RevertError.
+func revert(ctx context.Context, github github.API, artifacts []journal.Artifact) error {
+	failed := &RevertError{}
+	for i := len(artifacts) - 1; i >= 0; i-- {
+		a := artifacts[i]
+		logger := util.Logger(ctx).WithFields(log.Fields{
+			"kind": a.Kind,
+			"url":  a.URL,
+		})
+
+		var blocked error
+		for _, f := range failed.Remaining {
+			if dependsOn(a, f) {
+				blocked = fmt.Errorf("%s %s was not reverted", f.Kind, f.URL)
+			}
+		}
+		if blocked != nil {
+			logger.Warn("⚠️ Leaving the ", a.Kind, " in place because the ", blocked)
+			failed.Remaining = append(failed.Remaining, a)
+			failed.errs = append(failed.errs, blocked)
+			continue
+		}
+
+		var err error
+		switch a.Kind {
+		case journal.ArtifactPR:
+			err = revertPR(ctx, github, a)
+		case journal.ArtifactBranch:
+			err = revertBranch(ctx, github, a, artifacts)
+		case journal.ArtifactTag:
+			err = revertTag(ctx, github, a)
+		case journal.ArtifactRelease:
+			err = revertRelease(ctx, github, a)
+		case journal.ArtifactIssueComment:
+			logger.Info("Deleting the comment...")
+			err = github.DeleteIssueComment(ctx, a.Owner, a.Repo, a.ID)
+		case journal.ArtifactCommit:
+			if !hasArtifact(artifacts, journal.ArtifactBranch, a.Owner, a.Repo) {
+				logger.Warn("⚠️ The commit was pushed to a branch that kuboreleaser did not create, revert it manually if needed")
+			}
+		default:
+			logger.Info("Leaving the ", a.Kind, " in place, it cannot be reverted")
+		}
+		if err != nil {
+			// a cancelled context would fail all the remaining artifacts the same way
+			if ctx.Err() != nil {
+				return err
+			}
+			logger.Warn("⚠️ Failed to revert the ", a.Kind, ": ", err)
+			failed.Remaining = append(failed.Remaining, a)
+			failed.errs = append(failed.errs, err)
+			continue
+		}
+		failed.Reverted = append(failed.Reverted, a)
+	}
+	if len(failed.Remaining) > 0 {
+		return failed
+	}
+	return nil
+}
+
+func hasArtifact(artifacts []journal.Artifact, kind journal.ArtifactKind, owner, repo string) bool {
+	for _, a := range artifacts {
+		if a.Kind == kind && a.Owner == owner && a.Repo == repo {
+			return true
+		}
+	}
+	return false
+}
+
+func revertPR(ctx context.Context, github github.API, a journal.Artifact) error {
+	pr, err := github.GetPRByNumber(ctx, a.Owner, a.Repo, int(a.ID))
+	if err != nil {
+		return err
+	}
+	if pr == nil || pr.GetState() == "closed" && !pr.GetMerged() {
+		util.Logger(ctx).WithField("url", a.URL).Info("The PR is closed already")
+		return nil
+	}
+	if pr.GetMerged() {
+		return fmt.Errorf("🚨 %s is merged already, it has to be reverted manually", a.URL)
+	}
+
+	util.Logger(ctx).WithField("url", a.URL).Info("Closing the PR...")
+	return github.ClosePR(ctx, pr)
+}
+
+func revertBranch(ctx context.Context, github github.API, a journal.Artifact, artifacts []journal.Artifact) error {
+	branch, err := github.GetBranch(ctx, a.Owner, a.Repo, a.Name)
+	if err != nil {
+		return err
+	}
+	if branch == nil {
+		util.Logger(ctx).WithField("url", a.URL).Info("The branch is deleted already")
+		return nil
+	}
+
+	// the branch is ours as long as it points at the commit it was created from or at one of the commits we pushed
+	sha := branch.GetCommit().GetSHA()
+	ours := sha == a.SHA
+	for _, c := range artifacts {
+		if c.Kind == journal.ArtifactCommit && c.Owner == a.Owner && c.Repo == a.Repo && c.Name == sha {
+			ours = true
+		}
+	}
+	if !ours {
+		return fmt.Errorf("🚨 %s has commits that kuboreleaser did not push, it has to be deleted manually", a.URL)
+	}
+
+	util.Logger(ctx).WithField("url", a.URL).Info("Deleting the branch...")
+	return github.DeleteBranch(ctx, a.Owner, a.Repo, a.Name)
+}
+
+func revertTag(ctx context.Context, github github.API, a journal.Artifact) error {
+	tag, err := github.GetTag(ctx, a.Owner, a.Repo, a.Name)
+	if err != nil {
+		return err
+	}
+	if tag == nil {
+		util.Logger(ctx).WithField("url", a.URL).Info("The tag is deleted already")
+		return nil
+	}
+
+	release, err := github.GetRelease(ctx, a.Owner, a.Repo, a.Name)
+	if err != nil {
+		return err
+	}
+	if release != nil {
+		return fmt.Errorf("🚨 tag %s is used by %s, revert the release first", a.Name, release.GetHTMLURL())
+	}
+	runs, err := github.CountWorkflowRuns(ctx, a.Owner, a.Repo, a.Name)
+	if err != nil {
+		return err
+	}
+	if runs > 0 {
+		return fmt.Errorf("🚨 tag %s triggered %d workflow runs in %s/%s/%s/actions already, it has to be deleted manually", a.Name, runs, util.GitHubServerURL(), a.Owner, a.Repo)
+	}
+
+	util.Logger(ctx).WithField("url", a.URL).Info("Deleting the tag...")
+	return github.DeleteTag(ctx, a.Owner, a.Repo, a.Name)
+}
+
+func revertRelease(ctx context.Context, github github.API, a journal.Artifact) error {
+	release, err := github.GetRelease(ctx, a.Owner, a.Repo, a.Name)
+	if err != nil {
+		return err
+	}
+	if release == nil || release.GetID() != a.ID {
+		util.Logger(ctx).WithField("url", a.URL).Info("The release is deleted already")
+		return nil
+	}
+	// once the release is published, people might have seen it or downloaded its assets already
+	if !release.GetDraft() {
+		return fmt.Errorf("🚨 %s is published already, it has to be deleted manually", a.URL)
+	}
+
+	util.Logger(ctx).WithField("url", a.URL).Info("Deleting the release...")
+	return github.DeleteRelease(ctx, a.Owner, a.Repo, release)
+}
@@ -0,0 +1,112 @@
+package actions
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	gh "github.com/google/go-github/v48/github"
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/util"
+)
+
+// ReleaseTeamEnv lists who reviews the PRs kuboreleaser opens, separated by commas, e.g. alice,bob,@ipfs/kubo-maintainers
+const ReleaseTeamEnv = "KUBORELEASER_RELEASE_TEAM"
+
+// assignReviewers requests reviews from the release team and from the code owners of the files the PR touches,
+// and assigns the PR to whoever drives the release.
+// Failures are only warnings since the reviewers can still be requested by hand.
+func assignReviewers(ctx context.Context, github github.API, pr *gh.PullRequest) {
+	logger := util.Logger(ctx).WithField("url", pr.GetHTMLURL())
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+
+	var owners []string
+	for _, member := range strings.Split(util.Getenv(ReleaseTeamEnv, ""), ",") {
+		if member = strings.TrimSpace(member); member != "" {
+			owners = append(owners, member)
+		}
+	}
+	codeowners, err := getCodeowners(ctx, github, pr)
+	if err != nil {
+		logger.Warn("⚠️ Failed to get the code owners of the PR: ", err)
+	}
+	owners = append(owners, codeowners...)
+
+	login, err := github.GetLogin(ctx)
+	if err != nil {
+		logger.Warn("⚠️ Failed to get the login of the release driver: ", err)
+	}
+
+	// the author of the PR cannot review it
+	skip := map[string]bool{strings.ToLower(pr.GetUser().GetLogin()): true, "": true}
+	var reviewers, teams []string
+	for _, o := range owners {
+		o = strings.TrimPrefix(o, "@")
+		org, team, isTeam := strings.Cut(o, "/")
+		switch {
+		case strings.Contains(o, "@"):
+			logger.Debug("Skipping the code owner ", o, " because only GitHub users and teams can be requested")
+		case isTeam && !strings.EqualFold(org, owner):
+			logger.Debug("Skipping the team ", o, " because it does not belong to ", owner)
+		case isTeam && !skip["@"+strings.ToLower(team)]:
+			skip["@"+strings.ToLower(team)] = true
+			teams = append(teams, team)
+		case !isTeam && !skip[strings.ToLower(o)]:
+			skip[strings.ToLower(o)] = true
+			reviewers = append(reviewers, o)
+		}
+	}
+
+	if len(reviewers) > 0 || len(teams) > 0 {
+		err = github.RequestReviewers(ctx, pr, reviewers, teams)
+		if err != nil {
+			logger.Warn("⚠️ Failed to request reviewers, request them manually: ", err)
+		}
+	}
+	if login != "" {
+		err = github.AddAssignees(ctx, pr, []string{login})
+		if err != nil {
+			logger.Warn("⚠️ Failed to assign the PR: ", err)
+		}
+	}
+}
+
+// getCodeowners returns the owners of the files the PR touches according to the CODEOWNERS file of its base branch
+func getCodeowners(ctx context.Context, client github.API, pr *gh.PullRequest) ([]string, error) {
+	// the PR does not exist in dry-run mode
+	if pr.GetNumber() == 0 {
+		return nil, nil
+	}
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+
+	var file *gh.RepositoryContent
+	for _, path := range github.CodeownersPaths {
+		f, err := client.GetFile(ctx, owner, repo, path, pr.GetBase().GetRef())
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, nil
+	}
+	content, err := base64.StdEncoding.DecodeString(*file.Content)
+	if err != nil {
+		return nil, err
+	}
+	codeowners := github.ParseCodeowners(string(content))
+
+	files, err := client.GetPRFiles(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	var owners []string
+	for _, f := range files {
+		owners = append(owners, codeowners.Owners(f)...)
+	}
+	return owners, nil
+}
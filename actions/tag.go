@@ -1,58 +1,60 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ipfs/kuboreleaser/git"
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type Tag struct {
-	Git     *git.Client
-	GitHub  *github.Client
-	Version *util.Version
+	Git      *git.Client
+	GitHub   github.API
+	Version  *util.Version
+	Prompter Prompter
 }
 
-func (ctx Tag) getBranch() string {
+func (a Tag) getBranch() string {
 	// NOTE: for patch releases (and prereleases), we should use the the version release branch because the release branch might be ahead already
-	if ctx.Version.IsPrerelease() || ctx.Version.IsPatch() {
-		return repos.Kubo.VersionReleaseBranch(ctx.Version)
+	if a.Version.IsPrerelease() || a.Version.IsPatch() {
+		return repos.Kubo.VersionReleaseBranch(a.Version)
 	} else {
 		return repos.Kubo.ReleaseBranch
 	}
 }
 
-func (ctx Tag) Check() error {
-	log.Info("I'm going to check if the signed tag for the release already exists.")
+func (a Tag) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the signed tag for the release already exists.")
 
-	tag, err := ctx.GitHub.GetTag(repos.Kubo.Owner, repos.Kubo.Repo, ctx.Version.String())
+	tag, err := a.GitHub.GetTag(ctx, repos.Kubo.Owner, repos.Kubo.Repo, a.Version.String())
 	if err != nil {
 		return err
 	}
 	if tag == nil {
-		return fmt.Errorf("⚠️ https://github.com/%s/%s/tags/%s does not exist (%w)", repos.Kubo.Owner, repos.Kubo.Repo, ctx.Version.String(), ErrIncomplete)
+		return fmt.Errorf("⚠️ %s/%s/%s/tags/%s does not exist (%w)", util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, a.Version.String(), ErrIncomplete)
 	}
 	return nil
 }
 
-func (ctx Tag) Run() error {
-	log.Info("I'm going to create a signed tag for the release.")
+func (a Tag) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a signed tag for the release.")
 
-	branch, err := ctx.GitHub.GetBranch(repos.Kubo.Owner, repos.Kubo.Repo, ctx.getBranch())
+	branch, err := a.GitHub.GetBranch(ctx, repos.Kubo.Owner, repos.Kubo.Repo, a.getBranch())
 	if err != nil {
 		return err
 	}
 	if branch == nil {
-		return fmt.Errorf("🚨 https://github.com/%s/%s/blob/%s does not exist", repos.Kubo.Owner, repos.Kubo.Repo, ctx.getBranch())
+		return fmt.Errorf("🚨 %s/%s/%s/blob/%s does not exist", util.GitHubServerURL(), repos.Kubo.Owner, repos.Kubo.Repo, a.getBranch())
 	}
 
 	sha := branch.GetCommit().GetSHA()
 
-	return ctx.Git.WithClone(repos.Kubo.Owner, repos.Kubo.Repo, branch.GetName(), sha, func(c *git.Clone) error {
-		ref, err := c.Tag(sha, ctx.Version.String(), fmt.Sprintf("Release %s", ctx.Version))
+	return a.Git.WithClone(ctx, repos.Kubo.Owner, repos.Kubo.Repo, branch.GetName(), sha, func(c *git.Clone) error {
+		ref, err := c.Tag(sha, a.Version.String(), fmt.Sprintf("Release %s", a.Version))
 		if err != nil {
 			return err
 		}
@@ -63,10 +65,14 @@ func (ctx Tag) Run() error {
 Signature: %s
 
 Please approve if the tag is correct. When you do, the tag will be pushed to the remote repository.`, ref, ref.PGPSignature)
-		if !util.Confirm(prompt) {
-			return fmt.Errorf("🚨 creation of tag '%s' was not confirmed correctly", ctx.Version.String())
+		if !Confirm(ctx, a.Prompter, Prompt{ID: "tag/push", Message: prompt}) {
+			return fmt.Errorf("🚨 creation of tag '%s' was not confirmed correctly", a.Version.String())
 		}
 
-		return c.PushTag(ctx.Version.String())
+		return c.PushTag(ctx, a.Version.String())
 	})
 }
+
+func (a Tag) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
+}
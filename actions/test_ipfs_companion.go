@@ -1,27 +1,42 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type TestIPFSCompanion struct {
-	GitHub  *github.Client
+	GitHub  github.API
 	Version *util.Version
+	Journal *journal.Entry
 }
 
-func (ctx TestIPFSCompanion) Check() error {
-	log.Info("I'm going to check if the workflow that tests IPFS Companion has run already.")
+func (a TestIPFSCompanion) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the workflow that tests IPFS Companion has run already.")
 
-	return CheckWorkflowRun(ctx.GitHub, repos.IPFSCompanion.Owner, repos.IPFSCompanion.Repo, repos.IPFSCompanion.DefaultBranch, repos.IPFSCompanion.WorkflowName, repos.IPFSCompanion.WorkflowJobName, fmt.Sprintf(" %s\n", ctx.Version.String()))
+	return CheckWorkflowRun(ctx, a.GitHub, a.Journal, repos.IPFSCompanion.Owner, repos.IPFSCompanion.Repo, repos.IPFSCompanion.DefaultBranch, repos.IPFSCompanion.WorkflowName, repos.IPFSCompanion.WorkflowJobName, "", fmt.Sprintf(" %s\n", a.Version.String()))
 }
 
-func (ctx TestIPFSCompanion) Run() error {
-	log.Info("I'm going to create a workflow run that tests IPFS Companion.")
+func (a TestIPFSCompanion) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a workflow run that tests IPFS Companion.")
 
-	return ctx.GitHub.CreateWorkflowRun(repos.IPFSCompanion.Owner, repos.IPFSCompanion.Repo, repos.IPFSCompanion.WorkflowName, repos.IPFSCompanion.DefaultBranch, github.WorkflowRunInput{Name: "kubo-version", Value: ctx.Version.String()})
+	_, err := a.GitHub.CreateWorkflowRun(ctx, repos.IPFSCompanion.Owner, repos.IPFSCompanion.Repo, repos.IPFSCompanion.WorkflowName, repos.IPFSCompanion.DefaultBranch, github.WorkflowRunInput{Name: "kubo-version", Value: a.Version.String()})
+	return err
+}
+
+func (a TestIPFSCompanion) Poll() Poll {
+	return WorkflowPoll
+}
+
+func (a TestIPFSCompanion) Follow(ctx context.Context, tail *WorkflowTail) error {
+	return tail.Follow(ctx, a.GitHub, a.Journal, repos.IPFSCompanion.Owner, repos.IPFSCompanion.Repo, repos.IPFSCompanion.DefaultBranch, repos.IPFSCompanion.WorkflowName)
+}
+
+func (a TestIPFSCompanion) Retry(ctx context.Context, retries int) (bool, error) {
+	return RetryWorkflowRun(ctx, a.GitHub, a.Journal, repos.IPFSCompanion.Owner, repos.IPFSCompanion.Repo, repos.IPFSCompanion.DefaultBranch, repos.IPFSCompanion.WorkflowName, retries)
 }
@@ -1,35 +1,43 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/ipfs/kuboreleaser/git"
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type UpdateIPFSBlog struct {
-	Git     *git.Client
-	GitHub  *github.Client
-	Version *util.Version
-	Date    *time.Time
+	Git      *git.Client
+	GitHub   github.API
+	Version  *util.Version
+	Date     *time.Time
+	Prompter Prompter
 }
 
-func (ctx UpdateIPFSBlog) Check() error {
-	log.Info("I'm going to check if the workflow that updates the IPFS Blog has run already.")
+func (a UpdateIPFSBlog) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the workflow that updates the IPFS Blog has run already.")
 
-	return CheckPR(ctx.GitHub, repos.IPFSBlog.Owner, repos.IPFSBlog.Repo, repos.IPFSBlog.KuboBranch(ctx.Version), !ctx.Version.IsPrerelease())
+	// the blog is only updated for final releases, the registry skips this action for prereleases
+	return CheckPR(ctx, a.GitHub, repos.IPFSBlog.Owner, repos.IPFSBlog.Repo, repos.IPFSBlog.KuboBranch(a.Version), true)
 }
 
-func (ctx UpdateIPFSBlog) Run() error {
-	log.Info("I'm going to create a PR that updates the IPFS Blog and ask you to merge it for me.")
+func (a UpdateIPFSBlog) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a PR that updates the IPFS Blog and ask you to merge it for me.")
 
-	branch := repos.IPFSBlog.KuboBranch(ctx.Version)
-	title := fmt.Sprintf("Update Kubo: %s", ctx.Version)
-	body := fmt.Sprintf("This PR updates Kubo to %s", ctx.Version)
+	date := time.Now()
+	if a.Date != nil {
+		date = *a.Date
+	}
+
+	branch := repos.IPFSBlog.KuboBranch(a.Version)
+	title := fmt.Sprintf("Update Kubo: %s", a.Version)
+	body := withReleaseIssueRef(ctx, a.GitHub, a.Version, fmt.Sprintf("This PR updates Kubo to %s", a.Version))
 	command := util.Command{Name: "yq", Args: []string{
 		"ea",
 		"-i",
@@ -39,30 +47,36 @@ func (ctx UpdateIPFSBlog) Run() error {
 			"title": "Just released: Kubo %s!",
 			"date": "%s",
 			"publish_date": null,
-			"path": "https://github.com/ipfs/kubo/releases/tag/%s",
+			"path": "%s",
 			"tags": [
 				"go-ipfs",
 				"kubo"
 			]
-		}]} *+ .[0], "---"] | .[]`, ctx.Version.String()[1:], ctx.Date.Format("2006-01-02"), ctx.Version.String()),
+		}]} *+ .[0], "---"] | .[]`, a.Version.String()[1:], date.Format("2006-01-02"), repos.Kubo.ReleaseURL(a.Version)),
 		"src/_blog/releasenotes.md",
 	}}
-	b, err := ctx.GitHub.GetOrCreateBranch(repos.IPFSBlog.Owner, repos.IPFSBlog.Repo, branch, repos.IPFSBlog.DefaultBranch)
+	b, err := a.GitHub.GetOrCreateBranch(ctx, repos.IPFSBlog.Owner, repos.IPFSBlog.Repo, branch, repos.IPFSBlog.DefaultBranch)
 	if err != nil {
 		return err
 	}
 
-	err = ctx.Git.RunAndPush(repos.IPFSBlog.Owner, repos.IPFSBlog.Repo, branch, b.GetCommit().GetSHA(), "chore: add Kubo release note", command)
+	err = a.Git.RunAndPush(ctx, repos.IPFSBlog.Owner, repos.IPFSBlog.Repo, branch, b.GetCommit().GetSHA(), "chore: add Kubo release note", command)
 	if err != nil {
 		return err
 	}
 
-	pr, err := ctx.GitHub.GetOrCreatePR(repos.IPFSBlog.Owner, repos.IPFSBlog.Repo, branch, repos.IPFSBlog.DefaultBranch, title, body, false)
+	pr, err := a.GitHub.GetOrCreatePR(ctx, repos.IPFSBlog.Owner, repos.IPFSBlog.Repo, branch, repos.IPFSBlog.DefaultBranch, title, body, false)
 	if err != nil {
 		return err
 	}
-	if !util.ConfirmPR(pr) {
+	assignReviewers(ctx, a.GitHub, pr)
+	enableAutoMerge(ctx, a.GitHub, pr, github.MergeMethodSquash)
+	if !Confirm(ctx, a.Prompter, PRPrompt("update-ipfs-blog/merge-pr", pr)) {
 		return fmt.Errorf("🚨 %s not merged", pr.GetHTMLURL())
 	}
 	return nil
 }
+
+func (a UpdateIPFSBlog) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
+}
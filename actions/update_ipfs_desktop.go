@@ -1,45 +1,56 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ipfs/kuboreleaser/git"
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type UpdateIPFSDesktop struct {
 	Git     *git.Client
-	GitHub  *github.Client
+	GitHub  github.API
 	Version *util.Version
 }
 
-func (ctx UpdateIPFSDesktop) Check() error {
-	log.Info("I'm going to check if the PR that updates Kubo in IPFS Desktop has been created already.")
+func (a UpdateIPFSDesktop) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the PR that updates Kubo in IPFS Desktop has been created already.")
 
-	return CheckPR(ctx.GitHub, repos.IPFSDesktop.Owner, repos.IPFSDesktop.Repo, repos.IPFSDesktop.KuboBranch(ctx.Version), false)
+	return CheckPR(ctx, a.GitHub, repos.IPFSDesktop.Owner, repos.IPFSDesktop.Repo, repos.IPFSDesktop.KuboBranch(a.Version), false)
 }
 
-func (ctx UpdateIPFSDesktop) Run() error {
-	log.Info("I'm going to create a PR that updates Kubo in IPFS Desktop.")
+func (a UpdateIPFSDesktop) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a PR that updates Kubo in IPFS Desktop.")
 
-	branch := repos.IPFSDesktop.KuboBranch(ctx.Version)
-	title := fmt.Sprintf("Update Kubo: %s", ctx.Version)
-	body := fmt.Sprintf("This PR updates Kubo to %s", ctx.Version)
-	command := util.Command{Name: "npm", Args: []string{"install", fmt.Sprintf("kubo@%s", ctx.Version), "--save", "--save-exact"}}
+	branch := repos.IPFSDesktop.KuboBranch(a.Version)
+	title := fmt.Sprintf("Update Kubo: %s", a.Version)
+	body := withReleaseIssueRef(ctx, a.GitHub, a.Version, fmt.Sprintf("This PR updates Kubo to %s", a.Version))
+	command := util.Command{Name: "npm", Args: []string{"install", fmt.Sprintf("kubo@%s", a.Version), "--save", "--save-exact"}}
 
-	b, err := ctx.GitHub.GetOrCreateBranch(repos.IPFSDesktop.Owner, repos.IPFSDesktop.Repo, branch, repos.IPFSDesktop.DefaultBranch)
+	b, err := a.GitHub.GetOrCreateBranch(ctx, repos.IPFSDesktop.Owner, repos.IPFSDesktop.Repo, branch, repos.IPFSDesktop.DefaultBranch)
 	if err != nil {
 		return err
 	}
 
-	err = ctx.Git.RunAndPush(repos.IPFSDesktop.Owner, repos.IPFSDesktop.Repo, branch, b.GetCommit().GetSHA(), "chore: update Kubo", command)
+	err = a.Git.RunAndPush(ctx, repos.IPFSDesktop.Owner, repos.IPFSDesktop.Repo, branch, b.GetCommit().GetSHA(), "chore: update Kubo", command)
 	if err != nil {
 		return err
 	}
 
-	_, err = ctx.GitHub.GetOrCreatePR(repos.IPFSDesktop.Owner, repos.IPFSDesktop.Repo, branch, repos.IPFSDesktop.DefaultBranch, title, body, ctx.Version.IsPrerelease())
-	return err
+	pr, err := a.GitHub.GetOrCreatePR(ctx, repos.IPFSDesktop.Owner, repos.IPFSDesktop.Repo, branch, repos.IPFSDesktop.DefaultBranch, title, body, a.Version.IsPrerelease())
+	if err != nil {
+		return err
+	}
+	assignReviewers(ctx, a.GitHub, pr)
+	// the draft PRs for the prereleases are left alone
+	enableAutoMerge(ctx, a.GitHub, pr, github.MergeMethodSquash)
+	return nil
+}
+
+func (a UpdateIPFSDesktop) Revert(ctx context.Context, artifacts []journal.Artifact) error {
+	return revert(ctx, a.GitHub, artifacts)
 }
@@ -1,27 +1,42 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/repos"
 	"github.com/ipfs/kuboreleaser/util"
-	log "github.com/sirupsen/logrus"
 )
 
 type UpdateIPFSDocs struct {
-	GitHub  *github.Client
+	GitHub  github.API
 	Version *util.Version
+	Journal *journal.Entry
 }
 
-func (ctx UpdateIPFSDocs) Check() error {
-	log.Info("I'm going to check if the workflow that updates the IPFS docs has run already.")
+func (a UpdateIPFSDocs) Check(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to check if the workflow that updates the IPFS docs has run already.")
 
-	return CheckWorkflowRun(ctx.GitHub, repos.IPFSDocs.Owner, repos.IPFSDocs.Repo, repos.IPFSDocs.DefaultBranch, repos.IPFSDocs.WorkflowName, repos.IPFSDocs.WorkflowJobName, fmt.Sprintf(" %s\n", ctx.Version.String()))
+	return CheckWorkflowRun(ctx, a.GitHub, a.Journal, repos.IPFSDocs.Owner, repos.IPFSDocs.Repo, repos.IPFSDocs.DefaultBranch, repos.IPFSDocs.WorkflowName, repos.IPFSDocs.WorkflowJobName, "", fmt.Sprintf(" %s\n", a.Version.String()))
 }
 
-func (ctx UpdateIPFSDocs) Run() error {
-	log.Info("I'm going to create a workflow run that updates the IPFS docs.")
+func (a UpdateIPFSDocs) Run(ctx context.Context) error {
+	util.Logger(ctx).Info("I'm going to create a workflow run that updates the IPFS docs.")
 
-	return ctx.GitHub.CreateWorkflowRun(repos.IPFSDocs.Owner, repos.IPFSDocs.Repo, repos.IPFSDocs.WorkflowName, repos.IPFSDocs.DefaultBranch)
+	_, err := a.GitHub.CreateWorkflowRun(ctx, repos.IPFSDocs.Owner, repos.IPFSDocs.Repo, repos.IPFSDocs.WorkflowName, repos.IPFSDocs.DefaultBranch)
+	return err
+}
+
+func (a UpdateIPFSDocs) Poll() Poll {
+	return WorkflowPoll
+}
+
+func (a UpdateIPFSDocs) Follow(ctx context.Context, tail *WorkflowTail) error {
+	return tail.Follow(ctx, a.GitHub, a.Journal, repos.IPFSDocs.Owner, repos.IPFSDocs.Repo, repos.IPFSDocs.DefaultBranch, repos.IPFSDocs.WorkflowName)
+}
+
+func (a UpdateIPFSDocs) Retry(ctx context.Context, retries int) (bool, error) {
+	return RetryWorkflowRun(ctx, a.GitHub, a.Journal, repos.IPFSDocs.Owner, repos.IPFSDocs.Repo, repos.IPFSDocs.DefaultBranch, repos.IPFSDocs.WorkflowName, retries)
 }
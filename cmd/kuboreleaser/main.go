@@ -1,90 +1,610 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/ipfs/kuboreleaser/actions"
+	"github.com/ipfs/kuboreleaser/events"
 	"github.com/ipfs/kuboreleaser/git"
 	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/matrix"
 	"github.com/ipfs/kuboreleaser/util"
 	"github.com/urfave/cli/v2"
 )
 
-func Execute(action actions.IAction, c *cli.Context) error {
+func getPlan(c *cli.Context) *util.Plan {
+	plan, ok := c.App.Metadata["plan"].(*util.Plan)
+	if !ok {
+		return nil
+	}
+	return plan
+}
+
+// newGitClient returns the git client shared by all the actions, so that the missing credentials are asked for only once
+func newGitClient(c *cli.Context) (*git.Client, error) {
+	if client, ok := c.App.Metadata["git"].(*git.Client); ok {
+		return client, nil
+	}
+	log.Debug("Initializing Git client...")
+	client, err := git.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	if plan := getPlan(c); plan != nil {
+		client = client.WithDryRun(plan)
+	}
+	c.App.Metadata["git"] = client
+	return client, nil
+}
+
+// newGitHubClient returns the GitHub client shared by all the actions, so that the missing credentials are asked for only once
+func newGitHubClient(c *cli.Context) (*github.Client, error) {
+	if client, ok := c.App.Metadata["github"].(*github.Client); ok {
+		return client, nil
+	}
+	log.Debug("Initializing GitHub client...")
+	client, err := github.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	if plan := getPlan(c); plan != nil {
+		client = client.WithDryRun(plan)
+	}
+	c.App.Metadata["github"] = client
+	return client, nil
+}
+
+// newPrompter returns the prompter shared by all the actions
+func newPrompter(c *cli.Context) (actions.Prompter, error) {
+	if prompter, ok := c.App.Metadata["prompter"].(actions.Prompter); ok {
+		return prompter, nil
+	}
+
+	var answers map[string]string
+	if path := c.String("answers-file"); path != "" {
+		var err error
+		answers, err = actions.ReadAnswers(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var prompter actions.Prompter
+	switch c.String("prompter") {
+	case "tty":
+		prompter = actions.AnswerPrompter{Answers: answers, Fallback: actions.TTYPrompter{}}
+	case "answers":
+		prompter = actions.AnswerPrompter{Answers: answers}
+	case "auto-approve":
+		prompter = actions.AutoApprover{}
+	default:
+		return nil, fmt.Errorf("🚨 unsupported prompter %s", c.String("prompter"))
+	}
+
+	if c.Bool("wait-for-prs") {
+		github, err := newGitHubClient(c)
+		if err != nil {
+			return nil, err
+		}
+		prompter = actions.WaitPrompter{GitHub: github, Poll: actions.DefaultPoll, Fallback: prompter}
+	}
+
+	c.App.Metadata["prompter"] = prompter
+	return prompter, nil
+}
+
+// lockMu guards the acquisition of the lock by the actions executed concurrently
+var lockMu sync.Mutex
+
+// lockNonce tells this process apart from the others in the holder of the lock
+var lockNonce = func() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+// acquireLock takes the release lock unless we hold it already, nothing is locked in dry-run mode because nothing is changed
+func acquireLock(c *cli.Context) error {
+	lockMu.Lock()
+	defer lockMu.Unlock()
+	if _, ok := c.App.Metadata["lock"]; ok || getPlan(c) != nil {
+		return nil
+	}
+	// only the release commands have a release to lock
+	version, ok := c.App.Metadata["version"].(*util.Version)
+	if !ok {
+		return nil
+	}
+
+	github, err := newGitHubClient(c)
+	if err != nil {
+		return err
+	}
+	login, err := github.GetLogin(c.Context)
+	if err != nil {
+		return err
+	}
+	holder := login
+	if hostname, err := os.Hostname(); err == nil {
+		holder = fmt.Sprintf("%s@%s", login, hostname)
+	}
+	// two kuboreleaser processes of the same maintainer on the same machine must not share the lock either
+	holder = fmt.Sprintf("%s#%s", holder, lockNonce)
+
+	// the release issue is recorded in the journal if the lock has to create it
+	lock, err := actions.AcquireLock(c.Context, github.WithRecorder(getJournalEntry(c, "lock")), version, holder, c.Bool("steal-lock"))
+	if err != nil {
+		return err
+	}
+	c.App.Metadata["lock"] = lock
+	return nil
+}
+
+func getLock(c *cli.Context) *actions.Lock {
+	lockMu.Lock()
+	defer lockMu.Unlock()
+	lock, ok := c.App.Metadata["lock"].(*actions.Lock)
+	if !ok {
+		return nil
+	}
+	return lock
+}
+
+// newAction constructs the registered action with the clients it requires, recording what they create in the journal
+func newAction(c *cli.Context, r actions.Registration) (actions.IAction, error) {
+	entry := getJournalEntry(c, r.Name)
+
+	prompter, err := newPrompter(c)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := actions.Clients{Prompter: prompter, Journal: entry}
+	if r.Has(actions.RequiresGit) {
+		git, err := newGitClient(c)
+		if err != nil {
+			return nil, err
+		}
+		clients.Git = git.WithRecorder(entry)
+	}
+	if r.Has(actions.RequiresGitHub) {
+		github, err := newGitHubClient(c)
+		if err != nil {
+			return nil, err
+		}
+		clients.GitHub = github.WithRecorder(entry)
+	}
+	if r.Has(actions.RequiresMatrix) && !c.Bool("skip-matrix") {
+		log.Debug("Initializing Matrix client...")
+		matrix, err := matrix.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		clients.Matrix = matrix
+	}
+
+	return r.New(clients, c.App.Metadata["version"].(*util.Version), c), nil
+}
+
+// actionCommands generates a release subcommand for every registered action
+func actionCommands() []*cli.Command {
+	var commands []*cli.Command
+	for _, r := range actions.Registry {
+		r := r
+		commands = append(commands, &cli.Command{
+			Name:  r.Name,
+			Usage: r.Usage,
+			Flags: r.Flags,
+			Action: func(c *cli.Context) error {
+				action, err := newAction(c, r)
+				if err != nil {
+					return err
+				}
+				return Execute(c.Context, r.Name, action, c)
+			},
+		})
+	}
+	return commands
+}
+
+// getPoll returns the poll strategy of the action with the values passed on the command line taking precedence
+func getPoll(c *cli.Context, action actions.IAction) actions.Poll {
+	poll := actions.GetPoll(action)
+	if c.IsSet("poll-initial-delay") {
+		poll.InitialDelay = c.Duration("poll-initial-delay")
+	}
+	if c.IsSet("poll-interval") {
+		poll.Interval = c.Duration("poll-interval")
+	}
+	if c.IsSet("poll-multiplier") {
+		poll.Multiplier = c.Float64("poll-multiplier")
+	}
+	if c.IsSet("poll-max-interval") {
+		poll.MaxInterval = c.Duration("poll-max-interval")
+	}
+	if c.IsSet("poll-jitter") {
+		poll.Jitter = c.Float64("poll-jitter")
+	}
+	if c.IsSet("poll-deadline") {
+		poll.Deadline = c.Duration("poll-deadline")
+	}
+	if c.IsSet("retries") {
+		poll.Retries = c.Int("retries")
+	}
+	return poll
+}
+
+// followInterval is how often a workflow run is followed while waiting for the next check of the action
+const followInterval = 10 * time.Second
+
+// getFollow returns what follows the workflow run of the action with --follow, or nil if there is nothing to follow
+func getFollow(ctx context.Context, c *cli.Context, action actions.IAction) func() {
+	follower, ok := action.(actions.Follower)
+	if !ok || !c.Bool("follow") {
+		return nil
+	}
+	// stdout is reserved for the events with --log-format jsonl
+	tail := actions.NewWorkflowTail(util.Prefixed(ctx, os.Stderr))
+	return func() {
+		err := follower.Follow(ctx, tail)
+		if err != nil {
+			log.Debug("Failed to follow the workflow run: ", err)
+		}
+	}
+}
+
+// wait sleeps for the duration, following the workflow run every followInterval in the meantime if follow is set
+func wait(ctx context.Context, duration time.Duration, follow func()) error {
+	var tick <-chan time.Time
+	if follow != nil {
+		ticker := time.NewTicker(followInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	done := time.After(duration)
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-tick:
+			follow()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func getJournalEntry(c *cli.Context, name string) *journal.Entry {
+	j, ok := c.App.Metadata["journal"].(*journal.Journal)
+	if !ok {
+		return nil
+	}
+	return j.Entry(name)
+}
+
+// syncReleaseArtifacts updates the list of the PRs, tags, releases and workflow runs in the release issue after an action was run
+func syncReleaseArtifacts(ctx context.Context, c *cli.Context) {
+	j, ok := c.App.Metadata["journal"].(*journal.Journal)
+	version, hasVersion := c.App.Metadata["version"].(*util.Version)
+	if !ok || !hasVersion || getPlan(c) != nil {
+		return
+	}
+	github, err := newGitHubClient(c)
+	if err != nil {
+		log.Warn("⚠️ Failed to sync the release artifacts: ", err)
+		return
+	}
+	err = actions.SyncReleaseArtifacts(ctx, github, version, j)
+	if err != nil {
+		log.Warn("⚠️ Failed to sync the release artifacts: ", err)
+	}
+}
+
+func Execute(ctx context.Context, name string, action actions.IAction, c *cli.Context) (err error) {
+	// the actions log with util.Logger(ctx), so all their messages and the output of their commands carry the action's name
+	ctx = util.WithAction(ctx, name)
+	logger := util.Logger(ctx)
+	entry := getJournalEntry(c, name)
+
+	if version, ok := c.App.Metadata["version"].(*util.Version); ok {
+		ctx = events.WithAction(ctx, name, version.String())
+	} else {
+		ctx = events.WithAction(ctx, name, "")
+	}
+	defer func() {
+		if err != nil {
+			emit(ctx, events.PhaseError, nil, err)
+		}
+	}()
+	// the release issue is only synced when the action changed something, the Search API it needs has a low rate limit
+	ran := false
+	defer func(ctx context.Context) {
+		if ran {
+			syncReleaseArtifacts(ctx, c)
+		}
+	}(ctx)
+
+	if timeout := c.Duration("timeout"); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	check := func() error {
+		err := action.Check(ctx)
+		entry.Checked(actions.Status(err), err)
+		return err
+	}
+
+	// the lock is only taken once there is something to change
+	lock := func() error {
+		err := acquireLock(c)
+		if err != nil {
+			return err
+		}
+		if lock := getLock(c); lock != nil {
+			return lock.Held()
+		}
+		return nil
+	}
+
+	poll := getPoll(c, action)
+
+	// retry reruns what failed if the action is able to, otherwise it returns the failure
+	retry := func(checked *time.Time, attempt int, err error) error {
+		retrier, ok := action.(actions.Retrier)
+		if !ok || !errors.Is(err, actions.ErrFailure) || c.Bool("skip-wait") {
+			return err
+		}
+		retried, rerr := retrier.Retry(ctx, poll.Retries)
+		if rerr != nil {
+			return rerr
+		}
+		if !retried {
+			return err
+		}
+		events.Emit(ctx, events.Event{
+			StartedAt: checked,
+			Phase:     events.PhaseRetry,
+			Attempt:   attempt,
+			Delay:     poll.Delay(0).String(),
+			Class:     actions.ErrorClass(err),
+			Error:     err.Error(),
+			URLs:      events.URLs(err.Error()),
+		})
+		logger.Info("The action failed and is being retried, continuing...")
+		logger.Warn(err)
+		return nil
+	}
+
+	retried := false
 	if !c.Bool("skip-check-before") {
-		log.Info("Checking the status of the action...")
-		err := action.Check()
+		logger.Info("Checking the status of the action...")
+		start := time.Now()
+		err := check()
+		emit(ctx, events.PhaseCheckBefore, &start, err)
 		if err != nil {
-			if !errors.Is(err, actions.ErrIncomplete) {
-				return err
+			if errors.Is(err, actions.ErrIncomplete) {
+				logger.Info("The action is not complete yet, continuing...")
+				logger.Warn(err)
+			} else if errors.Is(err, actions.ErrFailure) && !c.Bool("skip-run") {
+				// a failure found when resuming the release is retried the same way as one found after the run
+				lerr := lock()
+				if lerr != nil {
+					return lerr
+				}
+				err = retry(&start, 0, err)
+				if err != nil {
+					return err
+				}
+				retried = true
 			} else {
-				log.Info("The action is not complete yet, continuing...")
-				log.Warn(err)
+				return err
 			}
 		} else {
-			log.Info("Action already completed")
+			logger.Info("Action already completed")
+			entry.Finish()
 			return nil
 		}
 	} else {
-		log.Info("Skipping the check before running the action")
+		logger.Info("Skipping the check before running the action")
 	}
 
-	if !c.Bool("skip-run") {
-		log.Info("Running the action...")
-		err := action.Run()
+	if retried {
+		logger.Info("Skipping the run of the action because what failed is being retried")
+	} else if !c.Bool("skip-run") {
+		err := lock()
 		if err != nil {
 			return err
 		}
+		logger.Info("Running the action...")
+		entry.Start()
+		start := time.Now()
+		err = action.Run(ctx)
+		emit(ctx, events.PhaseRun, &start, err, entry.URLs()...)
+		if err != nil {
+			return err
+		}
+		ran = true
 	} else {
-		log.Info("Skipping the run of the action")
+		logger.Info("Skipping the run of the action")
 	}
 
-	if !c.Bool("skip-check-after") {
-		duration := time.Second * 10
-		for {
-			log.Info("Sleeping for ", duration, "...")
-			time.Sleep(duration)
+	if getPlan(c) != nil {
+		logger.Info("Skipping the check after running the action because nothing was changed in dry-run mode")
+	} else if !c.Bool("skip-check-after") {
+		follow := getFollow(ctx, c, action)
+		start := time.Now()
+		var last error
+		duration := poll.Delay(0)
+		for attempt := 1; ; attempt++ {
+			if poll.Deadline > 0 && last != nil && time.Since(start)+duration > poll.Deadline {
+				return fmt.Errorf("🚨 gave up waiting for the action to complete after %s, the last status was: %w", time.Since(start).Round(time.Second), last)
+			}
 
-			duration = time.Minute * 1
+			logger.Info("Sleeping for ", duration, "...")
+			err := wait(ctx, duration, follow)
+			if err != nil {
+				return err
+			}
 
-			log.Info("Checking the status of the action...")
-			err := action.Check()
+			logger.Info("Checking the status of the action...")
+			checked := time.Now()
+			err = check()
+			if follow != nil {
+				follow()
+			}
 			if err != nil {
 				if errors.Is(err, actions.ErrInProgress) && !c.Bool("skip-wait") {
-					log.Info("The action is still in progress, continuing...")
-					log.Warn(err)
+					duration = poll.Delay(attempt)
+					events.Emit(ctx, events.Event{
+						StartedAt: &checked,
+						Phase:     events.PhasePoll,
+						Attempt:   attempt,
+						Delay:     duration.String(),
+						Class:     actions.ErrorClass(err),
+						Error:     err.Error(),
+						URLs:      events.URLs(err.Error()),
+					})
+					logger.Info("The action is still in progress, continuing...")
+					logger.Warn(err)
+					last = err
+					continue
+				}
+				rerr := retry(&checked, attempt, err)
+				if rerr == nil {
+					// the retry gets as much time to complete as the first attempt
+					start = time.Now()
+					duration = poll.Delay(0)
+					last = err
 					continue
 				}
+				err = rerr
+				emit(ctx, events.PhaseCheckAfter, &checked, err)
 				return err
 			}
-			log.Info("Action completed")
+			emit(ctx, events.PhaseCheckAfter, &checked, nil)
+			logger.Info("Action completed")
+			entry.Finish()
 			return nil
 		}
 	} else {
-		log.Info("Skipping the check after running the action")
+		logger.Info("Skipping the check after running the action")
 	}
 
 	return nil
 }
 
-func ExecuteAll(actions []actions.IAction, c *cli.Context) error {
-	// execute actions one by one, fail if any of them fails
-	for _, action := range actions {
-		err := Execute(action, c)
+// emit reports the outcome of a phase of the action to the event stream
+func emit(ctx context.Context, phase events.Phase, start *time.Time, err error, urls ...string) {
+	event := events.Event{
+		StartedAt: start,
+		Phase:     phase,
+		URLs:      urls,
+	}
+	if err != nil {
+		event.Class = actions.ErrorClass(err)
+		event.Error = err.Error()
+		event.URLs = append(event.URLs, events.URLs(err.Error())...)
+	}
+	events.Emit(ctx, event)
+}
+
+// ExecuteAll executes the named actions, running the ones which do not depend on each other concurrently
+func ExecuteAll(names []string, c *cli.Context) error {
+	version := c.App.Metadata["version"].(*util.Version)
+
+	graph, err := actions.ReleaseGraph().Subgraph(names...)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]actions.IAction)
+	for _, name := range names {
+		r, _ := actions.Lookup(name)
+		action, err := newAction(c, r)
 		if err != nil {
 			return err
 		}
+		byName[name] = action
 	}
+
+	return graph.Walk(c.Context, version, c.Int("jobs"), func(ctx context.Context, node actions.Node) error {
+		return Execute(ctx, node.Name, byName[node.Name], c)
+	})
+}
+
+// actionFormatter prefixes the messages logged on behalf of an action with its name
+type actionFormatter struct {
+	log.Formatter
+}
+
+func (f actionFormatter) Format(entry *log.Entry) ([]byte, error) {
+	name, ok := entry.Data["action"]
+	if !ok {
+		return f.Formatter.Format(entry)
+	}
+
+	prefixed := *entry
+	prefixed.Message = fmt.Sprintf("[%s] %s", name, entry.Message)
+	prefixed.Data = make(log.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		if k != "action" {
+			prefixed.Data[k] = v
+		}
+	}
+	return f.Formatter.Format(&prefixed)
+}
+
+// Walk executes the release subcommands in the order declared by the release graph.
+// If skipFinished is set, the subcommands which the journal marks as finished are not executed again.
+func Walk(c *cli.Context, skipFinished bool) error {
+	version := c.App.Metadata["version"].(*util.Version)
+	release := c.App.Command("release")
+
+	nodes, err := actions.ReleaseGraph().Sort(version)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if skipFinished && getJournalEntry(c, node.Name).IsFinished() {
+			log.Info("Skipping ", node.Name, " because the journal says it is completed already")
+			continue
+		}
+
+		command := release.Command(node.Name)
+		if command == nil {
+			return fmt.Errorf("🚨 release step %s does not exist", node.Name)
+		}
+
+		log.Info("Executing ", node.Name, "...")
+		ctx := cli.NewContext(c.App, nil, c)
+		ctx.Command = command
+		err := command.Run(ctx, command.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info("All the release steps are completed")
 	return nil
 }
 
-func main() {
-	app := &cli.App{
+func newApp() *cli.App {
+	return &cli.App{
 		Name:  "kuboreleaser",
 		Usage: "Kubo Release CLI",
 		Flags: []cli.Flag{
@@ -104,6 +624,61 @@ func main() {
 				Name:    "skip-wait",
 				Aliases: []string{"sw"},
 				Usage:   "skip the wait for the command to complete after the run",
+			}, &cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Usage:   "maximum number of actions to execute concurrently",
+				Value:   4,
+			}, &cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "maximum time a single action is allowed to take, including the wait for it to complete (0 means no limit)",
+			}, &cli.DurationFlag{
+				Name:  "poll-initial-delay",
+				Usage: "time to wait before the first check after the run (defaults depend on the action)",
+			}, &cli.DurationFlag{
+				Name:  "poll-interval",
+				Usage: "time to wait between the subsequent checks after the run (defaults depend on the action)",
+				Action: func(c *cli.Context, interval time.Duration) error {
+					return actions.ValidateInterval(interval)
+				},
+			}, &cli.Float64Flag{
+				Name:  "poll-multiplier",
+				Usage: "factor by which the poll interval grows after every check (defaults depend on the action)",
+			}, &cli.DurationFlag{
+				Name:  "poll-max-interval",
+				Usage: "maximum time to wait between the checks after the run (defaults depend on the action)",
+			}, &cli.Float64Flag{
+				Name:  "poll-jitter",
+				Usage: "fraction by which the poll interval is randomly shortened or extended, less than 1 (defaults depend on the action)",
+				Action: func(c *cli.Context, jitter float64) error {
+					return actions.ValidateJitter(jitter)
+				},
+			}, &cli.DurationFlag{
+				Name:  "poll-deadline",
+				Usage: "time after which to give up waiting for the action to complete, 0 means no deadline (defaults depend on the action)",
+			}, &cli.IntFlag{
+				Name:  "retries",
+				Usage: "how many times to rerun the failed jobs of a workflow run before giving up (defaults depend on the action)",
+			}, &cli.StringFlag{
+				Name:  "log-format",
+				Usage: "log format (text, or jsonl to write a stream of JSON events to stdout, the logs going to stderr)",
+				Value: "text",
+			}, &cli.StringFlag{
+				Name:  "prompter",
+				Usage: "how to answer the prompts: tty (ask unless answered in advance), answers (fail unless answered in advance) or auto-approve",
+				Value: "tty",
+			}, &cli.StringFlag{
+				Name:  "answers-file",
+				Usage: "file with the answers to the prompts, one '<prompt id>=yes' per line",
+			}, &cli.BoolFlag{
+				Name:  "wait-for-prs",
+				Usage: "instead of asking to confirm that a PR is merged, wait until it is",
+			}, &cli.BoolFlag{
+				Name:  "follow",
+				Usage: "while waiting for a workflow run, print the status of its jobs and steps and tail its logs",
+			}, &cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "plan the changes without performing them",
 			}, &cli.StringFlag{
 				Name:    "log-level",
 				Aliases: []string{"l"},
@@ -117,6 +692,36 @@ func main() {
 				return err
 			}
 			log.SetLevel(level)
+
+			switch c.String("log-format") {
+			case "text":
+			case "jsonl":
+				// the subcommands inherit the context, so that's how the stream reaches the actions
+				stream := events.NewStream(os.Stdout)
+				util.Output = os.Stderr
+				c.App.Metadata["events"] = stream
+				c.Context = events.WithStream(c.Context, stream)
+			default:
+				return fmt.Errorf("🚨 unsupported log format %s", c.String("log-format"))
+			}
+
+			if c.Bool("dry-run") {
+				log.Info("Running in dry-run mode, no changes will be made")
+				c.App.Metadata["plan"] = &util.Plan{}
+			}
+
+			return nil
+		},
+		After: func(c *cli.Context) error {
+			if lock := getLock(c); lock != nil {
+				// the context might be cancelled already, but we still want to clean up after ourselves
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				defer cancel()
+				lock.Release(ctx)
+			}
+			if plan := getPlan(c); plan != nil {
+				plan.Print(util.Output)
+			}
 			return nil
 		},
 		Commands: []*cli.Command{
@@ -125,7 +730,7 @@ func main() {
 				Usage: "Generate .env file in your current directory",
 				Action: func(c *cli.Context) error {
 					action := actions.Env{}
-					return Execute(action, c)
+					return Execute(c.Context, c.Command.Name, action, c)
 				},
 			},
 			{
@@ -137,6 +742,15 @@ func main() {
 						Aliases: []string{"v"},
 						Usage:   "Kubo version to release",
 					},
+					&cli.StringFlag{
+						Name:  "journal-dir",
+						Usage: "Directory where the release journals are stored",
+						Value: ".kuboreleaser",
+					},
+					&cli.BoolFlag{
+						Name:  "steal-lock",
+						Usage: "Take the release lock over even if someone else holds it",
+					},
 				},
 				Before: func(c *cli.Context) error {
 					log.Debug("Initializing version...")
@@ -147,361 +761,106 @@ func main() {
 
 					c.App.Metadata["version"] = version
 
+					if getPlan(c) != nil {
+						log.Debug("Skipping the journal in dry-run mode")
+						return nil
+					}
+
+					log.Debug("Initializing journal...")
+					j, err := journal.Open(c.String("journal-dir"), version.String())
+					if err != nil {
+						return err
+					}
+
+					c.App.Metadata["journal"] = j
+
 					return nil
 				},
-				Subcommands: []*cli.Command{
+				Subcommands: append([]*cli.Command{
 					{
-						Name:  "prepare-branch",
-						Usage: "Prepare a branch for the release",
+						Name:  "run",
+						Usage: "Run the whole release, skipping the steps that are completed already",
 						Action: func(c *cli.Context) error {
-							git, err := git.NewClient()
-							if err != nil {
-								return err
-							}
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.PrepareBranch{
-								Git:     git,
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
+							return Walk(c, false)
 						},
 					},
 					{
-						Name:  "promote",
-						Usage: "Promote the release",
+						Name:  "status",
+						Usage: "Check the status of every release step without running anything",
 						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "output",
+								Aliases: []string{"o"},
+								Usage:   "output format (table or json)",
+								Value:   "table",
+							},
 							&cli.BoolFlag{
 								Name:  "skip-matrix",
 								Usage: "Do not use Matrix client",
 								Value: util.GetenvBool("NO_MATRIX"),
 							},
 						},
-						Action: func(c *cli.Context) error {
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							var m *matrix.Client
-							if !c.Bool("skip-matrix") {
-								log.Debug("Initializing Matrix client...")
-								m, err = matrix.NewClient()
-								if err != nil {
-									return err
-								}
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.Promote{
-								GitHub:  github,
-								Matrix:  m,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
-					},
-					{
-						Name:  "publish-to-all",
-						Usage: "Publish the release to DockerHub, distributions, NPM, and GitHub",
-						Action: func(c *cli.Context) error {
-							git, err := git.NewClient()
-							if err != nil {
-								return err
-							}
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							actions := []actions.IAction{
-								&actions.PublishToDockerHub{
-									GitHub:  github,
-									Version: version,
-								},
-								&actions.PublishToDistributions{
-									Git:     git,
-									GitHub:  github,
-									Version: version,
-								},
-								&actions.PublishToNPM{
-									GitHub:  github,
-									Version: version,
-								},
-								&actions.PublishToGitHub{
-									GitHub:  github,
-									Version: version,
-								},
-							}
-
-							return ExecuteAll(actions, c)
-						},
-					},
-					{
-						Name:  "publish-to-distributions",
-						Usage: "Publish the release to distributions",
-						Action: func(c *cli.Context) error {
-							git, err := git.NewClient()
-							if err != nil {
-								return err
-							}
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.PublishToDistributions{
-								Git:     git,
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
-					},
-					{
-						Name:  "publish-to-github",
-						Usage: "Publish the release to GitHub",
-						Action: func(c *cli.Context) error {
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.PublishToGitHub{
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
-					},
-					{
-						Name:  "publish-to-npm",
-						Usage: "Publish the release to npm",
-						Action: func(c *cli.Context) error {
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.PublishToNPM{
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
-					},
-					{
-						Name:  "publish-to-dockerhub",
-						Usage: "Publish the release to DockerHub",
-						Action: func(c *cli.Context) error {
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.PublishToDockerHub{
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
+						Action: Status,
 					},
 					{
-						Name:  "tag",
-						Usage: "Tag the release",
+						Name:  "resume",
+						Usage: "Resume the release at the first step that is not completed according to the journal",
 						Action: func(c *cli.Context) error {
-							git, err := git.NewClient()
-							if err != nil {
-								return err
-							}
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.Tag{
-								Git:     git,
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
+							return Walk(c, true)
 						},
 					},
 					{
-						Name:  "test-ipfs-companion",
-						Usage: "Test the release with ipfs-companion",
-						Action: func(c *cli.Context) error {
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.TestIPFSCompanion{
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
-					},
-					{
-						Name:  "update-ipfs-desktop",
-						Usage: "Update the release in ipfs-desktop",
-						Action: func(c *cli.Context) error {
-							git, err := git.NewClient()
-							if err != nil {
-								return err
-							}
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.UpdateIPFSDesktop{
-								Git:     git,
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
-					},
-					{
-						Name:  "update-ipfs-docs",
-						Usage: "Update the release in ipfs-docs",
-						Action: func(c *cli.Context) error {
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.UpdateIPFSDocs{
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
-					},
-					{
-						Name:  "update-ipfs-blog",
-						Usage: "Update the release in ipfs-blog",
-						Flags: []cli.Flag{
-							&cli.TimestampFlag{
-								Name:    "date",
-								Aliases: []string{"d"},
-								Usage:   "Date of the release",
-								Layout:  "2006-01-02",
-							},
-						},
-						Action: func(c *cli.Context) error {
-							git, err := git.NewClient()
-							if err != nil {
-								return err
-							}
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.UpdateIPFSBlog{
-								Git:     git,
-								GitHub:  github,
-								Version: version,
-								Date:    c.Timestamp("date"),
-							}
-
-							return Execute(action, c)
-						},
-					},
-					{
-						Name:  "merge-branch",
-						Usage: "Merge the release branch into master",
-						Action: func(c *cli.Context) error {
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.MergeBranch{
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
-						},
+						Name:      "revert",
+						Usage:     "Undo what the action created according to the journal",
+						ArgsUsage: "<action>",
+						Action:    Revert,
 					},
 					{
-						Name:  "prepare-next",
-						Usage: "Prepare the next release",
+						Name:  "publish-to-all",
+						Usage: "Publish the release to DockerHub, distributions, NPM, and GitHub",
 						Action: func(c *cli.Context) error {
-							git, err := git.NewClient()
-							if err != nil {
-								return err
-							}
-							log.Debug("Initializing GitHub client...")
-							github, err := github.NewClient()
-							if err != nil {
-								return err
-							}
-							version := c.App.Metadata["version"].(*util.Version)
-
-							action := &actions.PrepareNext{
-								Git:     git,
-								GitHub:  github,
-								Version: version,
-							}
-
-							return Execute(action, c)
+							return ExecuteAll([]string{"publish-to-dockerhub", "publish-to-distributions", "publish-to-npm", "publish-to-github"}, c)
 						},
 					},
-				},
+				}, actionCommands()...),
 			},
 		},
 	}
+}
+
+func main() {
+	app := newApp()
 
 	formatter := &log.TextFormatter{
 		TimestampFormat: "2006-01-02 15:04:05",
 		FullTimestamp:   true,
 	}
-	log.SetFormatter(formatter)
-
-	if err := app.Run(os.Args); err != nil {
+	log.SetFormatter(actionFormatter{formatter})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		// restore the default behaviour so that the next signal terminates the process immediately
+		signal.Stop(signals)
+		log.Warn("Received ", sig, ", cancelling... Send it again to exit immediately.")
+		cancel()
+	}()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		// the failure of the whole command, unlike the failures of the actions, has no action name attached
+		if stream, ok := app.Metadata["events"].(*events.Stream); ok {
+			stream.Emit(events.Event{
+				Phase: events.PhaseError,
+				Class: actions.ErrorClass(err),
+				Error: err.Error(),
+				URLs:  events.URLs(err.Error()),
+			})
+		}
+		if errors.Is(err, github.ErrUnauthorized) {
+			log.Error("🚨 GitHub rejected the token, make sure GITHUB_TOKEN is valid and has the scopes kuboreleaser needs")
+		}
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ipfs/kuboreleaser/actions"
+	"github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/github/githubtest"
+	"github.com/ipfs/kuboreleaser/repos"
+	"github.com/ipfs/kuboreleaser/util"
+	"github.com/urfave/cli/v2"
+)
+
+// scriptedAction returns the errors in checks from its consecutive checks, repeating the last one
+type scriptedAction struct {
+	checks  []error
+	run     error
+	checked int
+	ran     int
+	retried int
+}
+
+func (a *scriptedAction) Check(ctx context.Context) error {
+	err := a.checks[min(a.checked, len(a.checks)-1)]
+	a.checked++
+	return err
+}
+
+func (a *scriptedAction) Run(ctx context.Context) error {
+	a.ran++
+	return a.run
+}
+
+func (a *scriptedAction) Poll() actions.Poll {
+	return actions.Poll{Interval: time.Second}
+}
+
+// retryingAction reruns what failed once
+type retryingAction struct {
+	*scriptedAction
+}
+
+func (a retryingAction) Retry(ctx context.Context, retries int) (bool, error) {
+	a.retried++
+	return a.retried == 1, nil
+}
+
+var (
+	errIncomplete = fmt.Errorf("not yet (%w)", actions.ErrIncomplete)
+	errInProgress = fmt.Errorf("running (%w)", actions.ErrInProgress)
+	errFailure    = fmt.Errorf("broken (%w)", actions.ErrFailure)
+)
+
+// execute runs the action as a command of the app with the flags
+func execute(action actions.IAction, flags ...string) error {
+	app := newApp()
+	app.Commands = append(app.Commands, &cli.Command{
+		Name: "test",
+		Action: func(c *cli.Context) error {
+			return Execute(c.Context, "test", action, c)
+		},
+	})
+	return app.RunContext(context.Background(), append(append([]string{"kuboreleaser"}, flags...), "test"))
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  *scriptedAction
+		retry   bool
+		flags   []string
+		want    string
+		checked int
+		ran     int
+		retried int
+	}{{
+		name:    "completed already",
+		action:  &scriptedAction{checks: []error{nil}},
+		want:    "",
+		checked: 1,
+		ran:     0,
+	}, {
+		name:    "run and wait until completed",
+		action:  &scriptedAction{checks: []error{errIncomplete, errInProgress, nil}},
+		want:    "",
+		checked: 3,
+		ran:     1,
+	}, {
+		name:    "check before failed",
+		action:  &scriptedAction{checks: []error{errFailure}},
+		want:    "ErrFailure",
+		checked: 1,
+		ran:     0,
+	}, {
+		name:    "failure found before run retried",
+		action:  &scriptedAction{checks: []error{errFailure, errInProgress, nil}},
+		retry:   true,
+		want:    "",
+		checked: 3,
+		ran:     0,
+		retried: 1,
+	}, {
+		name:    "failure found before run not retried with skip run",
+		action:  &scriptedAction{checks: []error{errFailure}},
+		retry:   true,
+		flags:   []string{"--skip-run"},
+		want:    "ErrFailure",
+		checked: 1,
+		ran:     0,
+	}, {
+		name:    "run failed",
+		action:  &scriptedAction{checks: []error{errIncomplete}, run: errors.New("boom")},
+		want:    "error",
+		checked: 1,
+		ran:     1,
+	}, {
+		name:    "check after failed",
+		action:  &scriptedAction{checks: []error{errIncomplete, errFailure}},
+		want:    "ErrFailure",
+		checked: 2,
+		ran:     1,
+	}, {
+		name:    "failure retried",
+		action:  &scriptedAction{checks: []error{errIncomplete, errFailure, nil}},
+		retry:   true,
+		want:    "",
+		checked: 3,
+		ran:     1,
+		retried: 1,
+	}, {
+		name:    "retries exhausted",
+		action:  &scriptedAction{checks: []error{errIncomplete, errFailure}},
+		retry:   true,
+		want:    "ErrFailure",
+		checked: 3,
+		ran:     1,
+		retried: 2,
+	}, {
+		name:    "skip run",
+		action:  &scriptedAction{checks: []error{errIncomplete, nil}},
+		flags:   []string{"--skip-run"},
+		want:    "",
+		checked: 2,
+		ran:     0,
+	}, {
+		name:    "skip check before",
+		action:  &scriptedAction{checks: []error{nil}},
+		flags:   []string{"--skip-check-before"},
+		want:    "",
+		checked: 1,
+		ran:     1,
+	}, {
+		name:    "skip wait",
+		action:  &scriptedAction{checks: []error{errIncomplete, errInProgress}},
+		flags:   []string{"--skip-wait"},
+		want:    "ErrInProgress",
+		checked: 2,
+		ran:     1,
+	}, {
+		name:    "deadline",
+		action:  &scriptedAction{checks: []error{errIncomplete, errInProgress}},
+		flags:   []string{"--poll-deadline", "1s"},
+		want:    "ErrInProgress",
+		checked: 2,
+		ran:     1,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var action actions.IAction = tt.action
+			if tt.retry {
+				action = retryingAction{tt.action}
+			}
+
+			err := execute(action, tt.flags...)
+			if got := actions.ErrorClass(err); got != tt.want {
+				t.Errorf("Execute() = %v, want %s", err, tt.want)
+			}
+			if tt.action.checked != tt.checked {
+				t.Errorf("checked %d times, want %d", tt.action.checked, tt.checked)
+			}
+			if tt.action.ran != tt.ran {
+				t.Errorf("ran %d times, want %d", tt.action.ran, tt.ran)
+			}
+			if tt.action.retried != tt.retried {
+				t.Errorf("retried %d times, want %d", tt.action.retried, tt.retried)
+			}
+		})
+	}
+}
+
+func TestExecuteWorkflowAction(t *testing.T) {
+	f := githubtest.NewFake()
+	f.WorkflowLogs[repos.NPMKubo.WorkflowName] = github.NewWorkflowRunLogs(map[string]string{
+		"0_" + repos.NPMKubo.WorkflowJobName + ".txt": "2024-01-01T00:00:00.0000000Z + kubo@0.30.0\n2024-01-01T00:00:01.0000000Z Published 0.30.0\n",
+	})
+	version, err := util.NewVersion("v0.30.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	action := actions.PublishToNPM{GitHub: f, Version: version}
+
+	err = execute(action, "--poll-initial-delay", "0s", "--poll-interval", "1s", "--poll-multiplier", "1", "--poll-jitter", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := f.CountWorkflowRuns(context.Background(), repos.NPMKubo.Owner, repos.NPMKubo.Repo, repos.NPMKubo.DefaultBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Errorf("dispatched %d workflow runs, want 1", runs)
+	}
+}
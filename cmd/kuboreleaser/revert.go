@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ipfs/kuboreleaser/actions"
+	"github.com/ipfs/kuboreleaser/journal"
+	"github.com/ipfs/kuboreleaser/util"
+	"github.com/urfave/cli/v2"
+)
+
+func Revert(c *cli.Context) error {
+	name := c.Args().First()
+	r, ok := actions.Lookup(name)
+	if !ok {
+		return fmt.Errorf("🚨 release step %s does not exist", name)
+	}
+
+	entry := getJournalEntry(c, name)
+	if entry == nil {
+		// in dry-run mode the journal is not opened for writing, but we still need to know what to revert
+		version := c.App.Metadata["version"].(*util.Version)
+		j, err := journal.Open(c.String("journal-dir"), version.String())
+		if err != nil {
+			return err
+		}
+		entry = j.Entry(name)
+	}
+	var artifacts []journal.Artifact
+	for _, a := range entry.GetArtifacts() {
+		if a.RevertedAt == nil {
+			artifacts = append(artifacts, a)
+		}
+	}
+	if len(artifacts) == 0 {
+		log.Info("The journal says ", name, " did not create anything that was not reverted already, there is nothing to revert")
+		return nil
+	}
+
+	err := acquireLock(c)
+	if err != nil {
+		return err
+	}
+	action, err := newAction(c, r)
+	if err != nil {
+		return err
+	}
+	reverter, ok := action.(actions.Reverter)
+	if !ok {
+		return fmt.Errorf("🚨 %s cannot be reverted", name)
+	}
+
+	var lines []string
+	for _, a := range artifacts {
+		lines = append(lines, fmt.Sprintf("- %s %s", a.Kind, a.URL))
+	}
+	prompter, err := newPrompter(c)
+	if err != nil {
+		return err
+	}
+	prompt := fmt.Sprintf(`%s created the following:
+%s
+
+Please approve to undo whatever of it can still be safely undone.`, name, strings.Join(lines, "\n"))
+	if !actions.Confirm(c.Context, prompter, actions.Prompt{ID: "revert/" + name, Message: prompt}) {
+		return fmt.Errorf("🚨 revert of %s was not confirmed correctly", name)
+	}
+
+	err = reverter.Revert(util.WithAction(c.Context, name), artifacts)
+	var partial *actions.RevertError
+	if errors.As(err, &partial) && getPlan(c) == nil {
+		entry.ArtifactsReverted(partial.Reverted)
+	}
+	if err != nil {
+		return err
+	}
+	if getPlan(c) == nil {
+		entry.Reverted()
+	}
+	log.Info("Reverted ", name)
+	return nil
+}
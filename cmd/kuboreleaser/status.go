@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ipfs/kuboreleaser/actions"
+	"github.com/ipfs/kuboreleaser/journal"
+	"github.com/ipfs/kuboreleaser/matrix"
+	"github.com/ipfs/kuboreleaser/util"
+	"github.com/urfave/cli/v2"
+)
+
+type ActionStatus struct {
+	Name   string         `json:"name"`
+	Status journal.Status `json:"status"`
+	Error  string         `json:"error,omitempty"`
+}
+
+func Status(c *cli.Context) error {
+	output := c.String("output")
+	if output != "table" && output != "json" {
+		return fmt.Errorf("🚨 unsupported output format %s", output)
+	}
+
+	version := c.App.Metadata["version"].(*util.Version)
+
+	github, err := newGitHubClient(c)
+	if err != nil {
+		return err
+	}
+	var m *matrix.Client
+	if !c.Bool("skip-matrix") {
+		log.Debug("Initializing Matrix client...")
+		m, err = matrix.NewClient()
+		if err != nil {
+			return err
+		}
+	}
+
+	nodes, err := actions.ReleaseGraph().Sort(version)
+	if err != nil {
+		return err
+	}
+
+	var statuses []ActionStatus
+	for _, node := range nodes {
+		r, _ := actions.Lookup(node.Name)
+		// the checks only need to read from GitHub and Matrix, and to record the IDs of the dispatched workflow runs they find
+		entry := getJournalEntry(c, node.Name)
+		action := r.New(actions.Clients{GitHub: github.WithRecorder(entry), Matrix: m, Journal: entry}, version, c)
+
+		ctx := util.WithAction(c.Context, node.Name)
+		util.Logger(ctx).Info("Checking the status of the action...")
+		err := action.Check(ctx)
+		if c.Context.Err() != nil {
+			return c.Context.Err()
+		}
+		entry.Checked(actions.Status(err), err)
+
+		status := ActionStatus{
+			Name:   node.Name,
+			Status: actions.Status(err),
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+
+	if output == "json" {
+		// the quota goes to the logs so that the output stays a list of statuses
+		for _, rate := range github.RateLimits() {
+			log.WithFields(log.Fields{
+				"remaining": rate.Remaining,
+				"limit":     rate.Limit,
+				"reset":     rate.Reset,
+			}).Info("GitHub ", rate.Resource, " API quota")
+		}
+		encoder := json.NewEncoder(util.Output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(statuses)
+	}
+
+	w := tabwriter.NewWriter(util.Output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tSTATUS\tDETAILS")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Status, s.Error)
+	}
+	if limits := github.RateLimits(); len(limits) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "GITHUB API\tREMAINING\tRESETS AT")
+		for _, rate := range limits {
+			fmt.Fprintf(w, "%s\t%d/%d\t%s\n", rate.Resource, rate.Remaining, rate.Limit, rate.Reset.Local().Format(time.TimeOnly))
+		}
+	}
+	return w.Flush()
+}
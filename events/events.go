@@ -0,0 +1,129 @@
+// Package events reports the progress of the actions as JSON lines, so that tools driving kuboreleaser do not have to parse its logs.
+// When the events go to stdout, everything else goes to stderr, see util.Output.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Phase string
+
+const (
+	PhaseCheckBefore Phase = "check-before"
+	PhaseRun         Phase = "run"
+	PhaseCheckAfter  Phase = "check-after"
+	PhasePoll        Phase = "poll"
+	PhaseRetry       Phase = "retry"
+	PhaseConfirm     Phase = "confirm"
+	PhaseError       Phase = "error"
+)
+
+type Event struct {
+	Time      time.Time  `json:"time"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Action    string     `json:"action,omitempty"`
+	Version   string     `json:"version,omitempty"`
+	Phase     Phase      `json:"phase"`
+	// Attempt is the number of the check after the run, counting from 1, a retry of a failure found before the run has none
+	Attempt int `json:"attempt,omitempty"`
+	// Delay is how long we are going to wait before the next check
+	Delay string `json:"delay,omitempty"`
+	// Prompt is the ID of the prompt
+	Prompt   string   `json:"prompt,omitempty"`
+	Approved *bool    `json:"approved,omitempty"`
+	Class    string   `json:"error_class,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	URLs     []string `json:"urls,omitempty"`
+}
+
+// Stream writes the events as JSON lines
+type Stream struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewStream(w io.Writer) *Stream {
+	return &Stream{encoder: json.NewEncoder(w)}
+}
+
+func (s *Stream) Emit(event Event) {
+	if s == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.encoder.Encode(event)
+	if err != nil {
+		log.Warn("Failed to emit event: ", err)
+	}
+}
+
+type key struct{}
+
+type scope struct {
+	stream  *Stream
+	action  string
+	version string
+}
+
+func getScope(ctx context.Context) scope {
+	s, _ := ctx.Value(key{}).(scope)
+	return s
+}
+
+// WithStream returns a context whose events are written to the stream
+func WithStream(ctx context.Context, stream *Stream) context.Context {
+	s := getScope(ctx)
+	s.stream = stream
+	return context.WithValue(ctx, key{}, s)
+}
+
+// WithAction returns a context whose events are attributed to the action releasing the version
+func WithAction(ctx context.Context, action, version string) context.Context {
+	s := getScope(ctx)
+	s.action = action
+	s.version = version
+	return context.WithValue(ctx, key{}, s)
+}
+
+func Enabled(ctx context.Context) bool {
+	return getScope(ctx).stream != nil
+}
+
+// Emit writes the event to the stream of the context, if there is one
+func Emit(ctx context.Context, event Event) {
+	s := getScope(ctx)
+	if s.stream == nil {
+		return
+	}
+	if event.Action == "" {
+		event.Action = s.action
+	}
+	if event.Version == "" {
+		event.Version = s.version
+	}
+	s.stream.Emit(event)
+}
+
+var urlRegexp = regexp.MustCompile(`https?://[^\s"'<>)]+`)
+
+// URLs returns the URLs mentioned in the text, e.g. in an error message
+func URLs(text string) []string {
+	urls := urlRegexp.FindAllString(text, -1)
+	for i, url := range urls {
+		urls[i] = strings.TrimRight(url, ".,;:")
+	}
+	return urls
+}
@@ -2,11 +2,13 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/util"
 	log "github.com/sirupsen/logrus"
 
@@ -18,10 +20,12 @@ import (
 )
 
 type Client struct {
-	name   string
-	email  string
-	auth   *HeaderAuth
-	entity *openpgp.Entity
+	name     string
+	email    string
+	auth     *HeaderAuth
+	entity   *openpgp.Entity
+	recorder journal.Recorder
+	plan     *util.Plan
 }
 
 func NewClient() (*Client, error) {
@@ -77,6 +81,36 @@ func NewClient() (*Client, error) {
 	}, nil
 }
 
+// WithRecorder returns a copy of the client which reports everything it pushes to the recorder.
+func (c *Client) WithRecorder(recorder journal.Recorder) *Client {
+	client := *c
+	client.recorder = recorder
+	return &client
+}
+
+// WithDryRun returns a copy of the client which adds every push to the plan instead of performing it.
+func (c *Client) WithDryRun(plan *util.Plan) *Client {
+	client := *c
+	client.plan = plan
+	return &client
+}
+
+// Plan adds the operation to the plan and returns true if the client is in dry-run mode.
+// Callers which mutate remote state without going through the client should skip the mutation when it returns true.
+func (c *Client) Plan(description string, fields log.Fields) bool {
+	if c.plan == nil {
+		return false
+	}
+	c.plan.Add(description, fields)
+	return true
+}
+
+func (c *Client) record(artifact journal.Artifact) {
+	if c.recorder != nil && c.plan == nil {
+		c.recorder.Record(artifact)
+	}
+}
+
 func (c *Client) signature() *object.Signature {
 	return &object.Signature{
 		Name:  c.name,
@@ -89,10 +123,19 @@ type Clone struct {
 	client     *Client
 	repository *git.Repository
 	dir        string
+	owner      string
+	repo       string
+	// logger attributes what happens in the clone to the action it was cloned for
+	logger *log.Entry
+
+	// what happened in the clone so far, reported if the work gets interrupted
+	commits []string
+	tags    []string
+	pushed  []string
 }
 
-func (c *Client) Clone(dir, owner, repo, branch, sha string) (*Clone, error) {
-	log.WithFields(log.Fields{
+func (c *Client) Clone(ctx context.Context, dir, owner, repo, branch, sha string) (*Clone, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"dir":    dir,
 		"owner":  owner,
 		"repo":   repo,
@@ -100,24 +143,24 @@ func (c *Client) Clone(dir, owner, repo, branch, sha string) (*Clone, error) {
 		"sha":    sha,
 	}).Debug("Cloning...")
 
-	log.Debug("Initializing git repository...")
+	util.Logger(ctx).Debug("Initializing git repository...")
 	repository, err := git.PlainInit(dir, false)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Debug("Adding remote...")
+	util.Logger(ctx).Debug("Adding remote...")
 	remote, err := repository.CreateRemote(&config.RemoteConfig{
 		Name: "origin",
-		URLs: []string{"https://github.com/" + owner + "/" + repo},
+		URLs: []string{util.GitHubRemote(owner, repo)},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	log.Debug("Fetching...")
+	util.Logger(ctx).Debug("Fetching...")
 	// https://github.com/go-git/go-git/issues/264
-	err = remote.Fetch(&git.FetchOptions{
+	err = remote.FetchContext(ctx, &git.FetchOptions{
 		Auth: c.auth,
 		RefSpecs: []config.RefSpec{
 			config.RefSpec("+" + sha + ":refs/remotes/origin/" + branch),
@@ -129,7 +172,7 @@ func (c *Client) Clone(dir, owner, repo, branch, sha string) (*Clone, error) {
 		return nil, err
 	}
 
-	log.Debug("Checking out...")
+	util.Logger(ctx).Debug("Checking out...")
 	worktree, err := repository.Worktree()
 	if err != nil {
 		return nil, err
@@ -143,17 +186,20 @@ func (c *Client) Clone(dir, owner, repo, branch, sha string) (*Clone, error) {
 		return nil, err
 	}
 
-	log.Debug("Cloned")
+	util.Logger(ctx).Debug("Cloned")
 
 	return &Clone{
 		client:     c,
 		repository: repository,
 		dir:        dir,
+		owner:      owner,
+		repo:       repo,
+		logger:     util.Logger(ctx),
 	}, nil
 }
 
 func (c *Clone) Status() (git.Status, error) {
-	log.Debug("Retrieving status...")
+	c.logger.Debug("Retrieving status...")
 
 	worktree, err := c.repository.Worktree()
 	if err != nil {
@@ -164,7 +210,7 @@ func (c *Clone) Status() (git.Status, error) {
 		return nil, err
 	}
 
-	log.WithFields(log.Fields{
+	c.logger.WithFields(log.Fields{
 		"status": status,
 	}).Debug("Retrieved status")
 
@@ -172,12 +218,12 @@ func (c *Clone) Status() (git.Status, error) {
 }
 
 func (c *Clone) Commit(glob, message string) (*object.Commit, error) {
-	log.WithFields(log.Fields{
+	c.logger.WithFields(log.Fields{
 		"glob":    glob,
 		"message": message,
 	}).Debug("Committing...")
 
-	log.Debug("Adding files...")
+	c.logger.Debug("Adding files...")
 	worktree, err := c.repository.Worktree()
 	if err != nil {
 		return nil, err
@@ -189,7 +235,7 @@ func (c *Clone) Commit(glob, message string) (*object.Commit, error) {
 		return nil, err
 	}
 
-	log.Debug("Creating commit...")
+	c.logger.Debug("Creating commit...")
 	hash, err := worktree.Commit(message, &git.CommitOptions{
 		Author: c.client.signature(),
 	})
@@ -197,15 +243,17 @@ func (c *Clone) Commit(glob, message string) (*object.Commit, error) {
 		return nil, err
 	}
 
-	log.WithFields(log.Fields{
+	c.logger.WithFields(log.Fields{
 		"hash": hash,
 	}).Debug("Commit created")
 
+	c.commits = append(c.commits, hash.String())
+
 	return c.repository.CommitObject(hash)
 }
 
 func (c *Clone) Tag(ref, tag, message string) (*object.Tag, error) {
-	log.WithFields(log.Fields{
+	c.logger.WithFields(log.Fields{
 		"ref": ref,
 		"tag": tag,
 	}).Debug("Tagging...")
@@ -217,28 +265,38 @@ func (c *Clone) Tag(ref, tag, message string) (*object.Tag, error) {
 	if c.client.entity != nil {
 		options.SignKey = c.client.entity
 	} else {
-		log.Warn("No OpenPGP key found, tag will not be signed")
+		c.logger.Warn("No OpenPGP key found, tag will not be signed")
 	}
 
-	log.Debug("Creating tag...")
+	c.logger.Debug("Creating tag...")
 	obj, err := c.repository.CreateTag(tag, plumbing.NewHash(ref), options)
 	if err != nil {
 		return nil, err
 	}
 
-	log.WithFields(log.Fields{
+	c.logger.WithFields(log.Fields{
 		"hash": obj.Hash(),
 	}).Debug("Tag created")
 
+	c.tags = append(c.tags, tag)
+
 	return c.repository.TagObject(obj.Hash())
 }
 
-func (c *Clone) Push(ref string) error {
-	log.WithFields(log.Fields{
+func (c *Clone) Push(ctx context.Context, ref string) error {
+	util.Logger(ctx).WithFields(log.Fields{
 		"ref": ref,
 	}).Debug("Pushing...")
 
-	err := c.repository.Push(&git.PushOptions{
+	if c.client.Plan(fmt.Sprintf("push %s to %s/%s/%s", ref, util.GitHubServerURL(), c.owner, c.repo), log.Fields{
+		"owner": c.owner,
+		"repo":  c.repo,
+		"ref":   ref,
+	}) {
+		return nil
+	}
+
+	err := c.repository.PushContext(ctx, &git.PushOptions{
 		Auth:       c.client.auth,
 		RemoteName: "origin",
 		RefSpecs: []config.RefSpec{
@@ -246,41 +304,98 @@ func (c *Clone) Push(ref string) error {
 		},
 	})
 
-	if err != nil {
-		log.Debug("Pushed")
+	if err == nil {
+		util.Logger(ctx).Debug("Pushed")
+		c.pushed = append(c.pushed, ref)
 	}
 
 	return err
 }
 
-func (c *Clone) PushBranch(branch string) error {
-	return c.Push(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch))
+func (c *Clone) PushBranch(ctx context.Context, branch string) error {
+	err := c.Push(ctx, fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch))
+	if err != nil {
+		return err
+	}
+
+	ref, err := c.repository.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return err
+	}
+	c.client.record(journal.Artifact{
+		Kind:  journal.ArtifactCommit,
+		Owner: c.owner,
+		Repo:  c.repo,
+		Name:  ref.Hash().String(),
+		URL:   util.GitHubURL("%s/%s/commit/%s", c.owner, c.repo, ref.Hash()),
+	})
+	return nil
 }
 
-func (c *Clone) PushTag(tag string) error {
-	return c.Push(fmt.Sprintf("refs/tags/%s:refs/tags/%s", tag, tag))
+func (c *Clone) PushTag(ctx context.Context, tag string) error {
+	err := c.Push(ctx, fmt.Sprintf("refs/tags/%s:refs/tags/%s", tag, tag))
+	if err != nil {
+		return err
+	}
+
+	c.client.record(journal.Artifact{
+		Kind:  journal.ArtifactTag,
+		Owner: c.owner,
+		Repo:  c.repo,
+		Name:  tag,
+		URL:   util.GitHubURL("%s/%s/releases/tag/%s", c.owner, c.repo, tag),
+	})
+	return nil
 }
 
-func (c *Client) WithClone(owner, repo, branch, sha string, fn func(*Clone) error) error {
+func (c *Client) WithClone(ctx context.Context, owner, repo, branch, sha string, fn func(*Clone) error) error {
 	dir, err := os.MkdirTemp("", "kuboreleaser")
 	if err != nil {
 		return err
 	}
 	defer os.RemoveAll(dir)
 
-	r, err := c.Clone(dir, owner, repo, branch, sha)
+	r, err := c.Clone(ctx, dir, owner, repo, branch, sha)
 	if err != nil {
 		return err
 	}
 
-	return fn(r)
+	err = fn(r)
+	if ctx.Err() != nil {
+		util.Logger(ctx).WithFields(log.Fields{
+			"owner":   owner,
+			"repo":    repo,
+			"branch":  branch,
+			"commits": r.commits,
+			"tags":    r.tags,
+			"pushed":  r.pushed,
+		}).Warn("Work on the clone was interrupted, removing ", dir, ". Only the pushed refs made it to the remote.")
+	}
+	return err
 }
 
-func (c *Client) RunAndPush(owner, repo, branch, sha, message string, commands ...util.Command) error {
-	return c.WithClone(owner, repo, branch, sha, func(r *Clone) error {
+func (c *Client) RunAndPush(ctx context.Context, owner, repo, branch, sha, message string, commands ...util.Command) error {
+	if c.plan != nil {
+		for _, command := range commands {
+			c.plan.Add(fmt.Sprintf("run %s %v on %s in %s/%s/%s", command.Name, command.Args, branch, util.GitHubServerURL(), owner, repo), log.Fields{
+				"owner":  owner,
+				"repo":   repo,
+				"branch": branch,
+				"sha":    sha,
+			})
+		}
+		c.plan.Add(fmt.Sprintf("commit the changes as '%s' and push them to %s in %s/%s/%s", message, branch, util.GitHubServerURL(), owner, repo), log.Fields{
+			"owner":  owner,
+			"repo":   repo,
+			"branch": branch,
+		})
+		return nil
+	}
+
+	return c.WithClone(ctx, owner, repo, branch, sha, func(r *Clone) error {
 		for _, command := range commands {
 			command.Dir = r.dir
-			err := command.Run()
+			err := command.Run(ctx)
 			if err != nil {
 				return err
 			}
@@ -297,7 +412,7 @@ func (c *Client) RunAndPush(owner, repo, branch, sha, message string, commands .
 				return err
 			}
 
-			return r.PushBranch(branch)
+			return r.PushBranch(ctx, branch)
 		}
 
 		return nil
@@ -0,0 +1,72 @@
+package github
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// API is the part of the client that the actions use, so that they can be exercised against a fake GitHub
+type API interface {
+	GetLogin(ctx context.Context) (string, error)
+
+	GetIssue(ctx context.Context, owner, repo, title string) (*github.Issue, error)
+	GetIssueByNumber(ctx context.Context, owner, repo string, number int) (*github.Issue, error)
+	CreateIssue(ctx context.Context, owner, repo, title, body string) (*github.Issue, error)
+	GetOrCreateIssue(ctx context.Context, owner, repo, title, body string) (*github.Issue, error)
+	UpdateIssue(ctx context.Context, owner, repo string, number int, body string) (*github.Issue, error)
+	GetIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error)
+	GetIssueComments(ctx context.Context, owner, repo string, number int) ([]*github.IssueComment, error)
+	GetIssueCommentByID(ctx context.Context, owner, repo string, id int64) (*github.IssueComment, error)
+	CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error)
+	GetOrCreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error)
+	UpdateIssueComment(ctx context.Context, owner, repo string, id int64, body string) (*github.IssueComment, error)
+	DeleteIssueComment(ctx context.Context, owner, repo string, id int64) error
+
+	GetBranch(ctx context.Context, owner, repo, name string) (*github.Branch, error)
+	CreateBranch(ctx context.Context, owner, repo, name, source string) (*github.Branch, error)
+	GetOrCreateBranch(ctx context.Context, owner, repo, name, source string) (*github.Branch, error)
+	DeleteBranch(ctx context.Context, owner, repo, name string) error
+
+	GetPR(ctx context.Context, owner, repo, head string) (*github.PullRequest, error)
+	GetPRByNumber(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error)
+	CreatePR(ctx context.Context, owner, repo, head, base, title, body string, draft bool) (*github.PullRequest, error)
+	GetOrCreatePR(ctx context.Context, owner, repo, head, base, title, body string, draft bool) (*github.PullRequest, error)
+	UpdatePR(ctx context.Context, pr *github.PullRequest) error
+	EnableAutoMerge(ctx context.Context, pr *github.PullRequest, method MergeMethod) error
+	GetPRFiles(ctx context.Context, pr *github.PullRequest) ([]string, error)
+	RequestReviewers(ctx context.Context, pr *github.PullRequest, reviewers, teams []string) error
+	AddAssignees(ctx context.Context, pr *github.PullRequest, assignees []string) error
+	ClosePR(ctx context.Context, pr *github.PullRequest) error
+
+	GetFile(ctx context.Context, owner, repo, path, ref string) (*github.RepositoryContent, error)
+	Compare(ctx context.Context, owner, repo, base, head string) ([]*github.RepositoryCommit, error)
+
+	GetCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error)
+	GetIncompleteCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error)
+	GetUnsuccessfulCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error)
+	GetRequiredStatusChecks(ctx context.Context, owner, repo, branch string) ([]string, error)
+	GetCommitStatuses(ctx context.Context, owner, repo, ref string) ([]*github.RepoStatus, error)
+
+	CreateWorkflowRun(ctx context.Context, owner, repo, file, ref string, inputs ...WorkflowRunInput) (*WorkflowRunHandle, error)
+	FindWorkflowRun(ctx context.Context, handle *WorkflowRunHandle) (*github.WorkflowRun, error)
+	GetWorkflowRun(ctx context.Context, owner, repo, branch, file string, completed bool) (*github.WorkflowRun, error)
+	GetWorkflowRunByID(ctx context.Context, owner, repo string, id int64) (*github.WorkflowRun, error)
+	CountWorkflowRuns(ctx context.Context, owner, repo, ref string) (int, error)
+	GetWorkflowRunLogs(ctx context.Context, owner, repo string, id int64, attempt int) (*WorkflowRunLogs, error)
+	RerunFailedJobs(ctx context.Context, owner, repo string, id int64) error
+	GetWorkflowRunJobs(ctx context.Context, owner, repo string, id int64) ([]*github.WorkflowJob, error)
+	GetWorkflowJobLogs(ctx context.Context, owner, repo string, id int64) (io.ReadCloser, error)
+
+	GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error)
+	GetRelease(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error)
+	CreateRelease(ctx context.Context, owner, repo, tag, name, body string, prerelease bool, latest bool) (*github.RepositoryRelease, error)
+	GetOrCreateRelease(ctx context.Context, owner, repo, tag, name, body string, prerelease bool, latest bool) (*github.RepositoryRelease, error)
+	DeleteRelease(ctx context.Context, owner, repo string, release *github.RepositoryRelease) error
+
+	GetTag(ctx context.Context, owner, repo, tag string) (*github.Tag, error)
+	DeleteTag(ctx context.Context, owner, repo, tag string) error
+}
+
+var _ API = (*Client)(nil)
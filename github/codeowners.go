@@ -0,0 +1,80 @@
+package github
+
+import (
+	"bufio"
+	"path"
+	"strings"
+)
+
+// CodeownersPaths are where GitHub looks for the CODEOWNERS file, in order
+var CodeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type codeownersRule struct {
+	pattern string
+	owners  []string
+}
+
+// Codeowners maps paths to their owners, e.g. @user, @org/team or an email
+type Codeowners []codeownersRule
+
+func ParseCodeowners(content string) Codeowners {
+	var rules Codeowners
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var owners []string
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "#") {
+				break
+			}
+			owners = append(owners, f)
+		}
+		rules = append(rules, codeownersRule{pattern: fields[0], owners: owners})
+	}
+	return rules
+}
+
+// Owners returns the owners of the file, the last matching rule wins like on GitHub
+func (c Codeowners) Owners(file string) []string {
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].matches(file) {
+			return c[i].owners
+		}
+	}
+	return nil
+}
+
+// matches supports the subset of the gitignore syntax that CODEOWNERS files use in practice
+func (r codeownersRule) matches(file string) bool {
+	pattern := r.pattern
+	if pattern == "*" {
+		return true
+	}
+	// a pattern with a slash other than at the end is relative to the root, otherwise it matches at any depth
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/**")
+
+	segments := strings.Split(file, "/")
+	for start := 0; start < len(segments); start++ {
+		if anchored && start > 0 {
+			break
+		}
+		// the pattern matches the file itself or one of its parent directories
+		for end := start + 1; end <= len(segments); end++ {
+			if dir && end == len(segments) {
+				break
+			}
+			if ok, _ := path.Match(pattern, strings.Join(segments[start:end], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
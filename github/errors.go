@@ -0,0 +1,61 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/go-github/v48/github"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrConflict     = errors.New("conflict")
+)
+
+// APIError is an error returned by the GitHub API, classified by its status code.
+// errors.Is matches it against both the matching Err* and the original error.
+type APIError struct {
+	Kind error
+	Err  error
+}
+
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *APIError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// wrapError classifies the error of a GitHub API call, the other errors are returned as they are
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var errResp *github.ErrorResponse
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &abuseErr):
+		kind = ErrRateLimited
+	case errors.As(err, &errResp) && errResp.Response != nil:
+		switch errResp.Response.StatusCode {
+		case http.StatusNotFound:
+			kind = ErrNotFound
+		case http.StatusUnauthorized, http.StatusForbidden:
+			kind = ErrUnauthorized
+		case http.StatusTooManyRequests:
+			kind = ErrRateLimited
+		case http.StatusConflict:
+			kind = ErrConflict
+		}
+	}
+	if kind == nil {
+		return err
+	}
+	return &APIError{Kind: kind, Err: err}
+}
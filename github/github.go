@@ -1,15 +1,18 @@
 package github
 
 import (
-	"archive/zip"
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/ipfs/kuboreleaser/journal"
 	"github.com/ipfs/kuboreleaser/util"
 	"github.com/shurcooL/githubv4"
 	log "github.com/sirupsen/logrus"
@@ -19,8 +22,13 @@ import (
 )
 
 type Client struct {
-	v3 *github.Client
-	v4 *githubv4.Client
+	v3       *github.Client
+	v4       *githubv4.Client
+	limits   *rateLimitTransport
+	recorder journal.Recorder
+	plan     *util.Plan
+	// workflows is shared by the copies of the client
+	workflows *workflowInputs
 }
 
 func NewClient() (*Client, error) {
@@ -30,15 +38,74 @@ func NewClient() (*Client, error) {
 		&oauth2.Token{AccessToken: token},
 	)
 	o2 := oauth2.NewClient(context.Background(), sts)
+	limits := newRateLimitTransport(o2.Transport)
+	o2.Transport = limits
+
+	// the URLs are used as given rather than through github.NewEnterpriseClient, which would append /api/v3 to the URL of a local stand-in
+	v3 := github.NewClient(o2)
+	baseURL, err := url.Parse(util.GitHubAPIURL() + "/")
+	if err != nil {
+		return nil, fmt.Errorf("🚨 invalid GITHUB_API_URL: %w", err)
+	}
+	uploadURL, err := url.Parse(util.GitHubUploadURL() + "/")
+	if err != nil {
+		return nil, fmt.Errorf("🚨 invalid GITHUB_UPLOAD_URL: %w", err)
+	}
+	v3.BaseURL = baseURL
+	v3.UploadURL = uploadURL
+
+	log.WithFields(log.Fields{
+		"api":     baseURL,
+		"graphql": util.GitHubGraphQLURL(),
+	}).Debug("Using GitHub API")
 
 	return &Client{
-		v3: github.NewClient(o2),
-		v4: githubv4.NewClient(o2),
+		v3:     v3,
+		v4:     githubv4.NewEnterpriseClient(util.GitHubGraphQLURL(), o2),
+		limits: limits,
+		workflows: &workflowInputs{
+			declared: make(map[string]bool),
+		},
 	}, nil
 }
 
-func (c *Client) GetIssue(owner, repo, title string) (*github.Issue, error) {
-	log.WithFields(log.Fields{
+// RateLimits returns the quotas reported by the responses received so far
+func (c *Client) RateLimits() []RateLimit {
+	return c.limits.RateLimits()
+}
+
+// GetLogin returns the login of the user the token belongs to
+func (c *Client) GetLogin(ctx context.Context) (string, error) {
+	user, _, err := c.v3.Users.Get(ctx, "")
+	err = wrapError(err)
+	if err != nil {
+		return "", err
+	}
+	return user.GetLogin(), nil
+}
+
+// WithRecorder returns a copy of the client which reports everything it creates to the recorder.
+func (c *Client) WithRecorder(recorder journal.Recorder) *Client {
+	client := *c
+	client.recorder = recorder
+	return &client
+}
+
+// WithDryRun returns a copy of the client which adds every mutating call to the plan instead of performing it.
+func (c *Client) WithDryRun(plan *util.Plan) *Client {
+	client := *c
+	client.plan = plan
+	return &client
+}
+
+func (c *Client) record(artifact journal.Artifact) {
+	if c.recorder != nil && c.plan == nil {
+		c.recorder.Record(artifact)
+	}
+}
+
+func (c *Client) GetIssue(ctx context.Context, owner, repo, title string) (*github.Issue, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"title": title,
@@ -50,7 +117,8 @@ func (c *Client) GetIssue(owner, repo, title string) (*github.Issue, error) {
 	q := fmt.Sprintf("is:issue repo:%s/%s in:title %s", owner, repo, title)
 	var issue *github.Issue
 	for {
-		is, r, err := c.v3.Search.Issues(context.Background(), q, opt)
+		is, r, err := c.v3.Search.Issues(ctx, q, opt)
+		err = wrapError(err)
 		if err != nil {
 			return nil, err
 		}
@@ -67,53 +135,113 @@ func (c *Client) GetIssue(owner, repo, title string) (*github.Issue, error) {
 	}
 
 	if issue != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": issue.GetHTMLURL(),
 		}).Debug("Found issue")
 	} else {
-		log.Debug("Issue not found")
+		util.Logger(ctx).Debug("Issue not found")
 	}
 
 	return issue, nil
 }
 
-func (c *Client) CreateIssue(owner, repo, title, body string) (*github.Issue, error) {
-	log.WithFields(log.Fields{
+// GetIssueByNumber goes through the core API, unlike GetIssue which has to use the Search API with its much lower rate limit
+func (c *Client) GetIssueByNumber(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+	}).Debug("Searching for issue...")
+
+	issue, _, err := c.v3.Issues.Get(ctx, owner, repo, number)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return issue, err
+}
+
+func (c *Client) CreateIssue(ctx context.Context, owner, repo, title, body string) (*github.Issue, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"title": title,
 		"body":  body,
 	}).Debug("Creating issue...")
 
-	issue, _, err := c.v3.Issues.Create(context.Background(), owner, repo, &github.IssueRequest{
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("create issue '%s' in %s/%s/%s", title, util.GitHubServerURL(), owner, repo), log.Fields{
+			"owner": owner,
+			"repo":  repo,
+			"title": title,
+		})
+		return &github.Issue{
+			Title: &title,
+			Body:  &body,
+		}, nil
+	}
+
+	issue, _, err := c.v3.Issues.Create(ctx, owner, repo, &github.IssueRequest{
 		Title: &title,
 		Body:  &body,
 	})
+	err = wrapError(err)
 
 	if issue != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": issue.GetHTMLURL(),
 		}).Debug("Created issue")
+		c.record(journal.Artifact{
+			Kind:  journal.ArtifactIssue,
+			Owner: owner,
+			Repo:  repo,
+			Name:  title,
+			ID:    int64(issue.GetNumber()),
+			URL:   issue.GetHTMLURL(),
+		})
 	} else {
-		log.Debug("Issue not created")
+		util.Logger(ctx).Debug("Issue not created")
 	}
 
 	return issue, err
 }
 
-func (c *Client) GetOrCreateIssue(owner, repo, title, body string) (*github.Issue, error) {
-	issue, err := c.GetIssue(owner, repo, title)
+func (c *Client) GetOrCreateIssue(ctx context.Context, owner, repo, title, body string) (*github.Issue, error) {
+	issue, err := c.GetIssue(ctx, owner, repo, title)
 	if err != nil {
 		return nil, err
 	}
 	if issue != nil {
 		return issue, nil
 	}
-	return c.CreateIssue(owner, repo, title, body)
+	return c.CreateIssue(ctx, owner, repo, title, body)
 }
 
-func (c *Client) GetIssueComment(owner, repo string, number int, body string) (*github.IssueComment, error) {
-	log.WithFields(log.Fields{
+// UpdateIssue replaces the body of the issue
+func (c *Client) UpdateIssue(ctx context.Context, owner, repo string, number int, body string) (*github.Issue, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+	}).Debug("Updating issue...")
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("update the description of %s", util.GitHubURL("%s/%s/issues/%d", owner, repo, number)), log.Fields{
+			"owner":  owner,
+			"repo":   repo,
+			"number": number,
+		})
+		return &github.Issue{Number: &number, Body: &body}, nil
+	}
+
+	issue, _, err := c.v3.Issues.Edit(ctx, owner, repo, number, &github.IssueRequest{
+		Body: &body,
+	})
+	return issue, wrapError(err)
+}
+
+func (c *Client) GetIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner":  owner,
 		"repo":   repo,
 		"number": number,
@@ -125,7 +253,8 @@ func (c *Client) GetIssueComment(owner, repo string, number int, body string) (*
 	}
 	var comment *github.IssueComment
 	for {
-		cs, r, err := c.v3.Issues.ListComments(context.Background(), owner, repo, number, opt)
+		cs, r, err := c.v3.Issues.ListComments(ctx, owner, repo, number, opt)
+		err = wrapError(err)
 		if err != nil {
 			return nil, err
 		}
@@ -142,107 +271,243 @@ func (c *Client) GetIssueComment(owner, repo string, number int, body string) (*
 	}
 
 	if comment != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": comment.GetHTMLURL(),
 		}).Debug("Found comment")
 	} else {
-		log.Debug("Comment not found")
+		util.Logger(ctx).Debug("Comment not found")
 	}
 
 	return comment, nil
 }
 
-func (c *Client) CreateIssueComment(owner, repo string, number int, body string) (*github.IssueComment, error) {
-	log.WithFields(log.Fields{
+func (c *Client) CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner":  owner,
 		"repo":   repo,
 		"number": number,
 		"body":   body,
 	}).Debug("Creating issue comment...")
 
-	comment, _, err := c.v3.Issues.CreateComment(context.Background(), owner, repo, number, &github.IssueComment{
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("comment on %s/%s/%s/issues/%d: %s", util.GitHubServerURL(), owner, repo, number, body), log.Fields{
+			"owner":  owner,
+			"repo":   repo,
+			"number": number,
+		})
+		return &github.IssueComment{
+			Body: &body,
+		}, nil
+	}
+
+	comment, _, err := c.v3.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{
 		Body: &body,
 	})
+	err = wrapError(err)
 
 	if comment != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": comment.GetHTMLURL(),
 		}).Debug("Created comment")
+		c.record(journal.Artifact{
+			Kind:  journal.ArtifactIssueComment,
+			Owner: owner,
+			Repo:  repo,
+			ID:    comment.GetID(),
+			URL:   comment.GetHTMLURL(),
+		})
 	} else {
-		log.Debug("Comment not created")
+		util.Logger(ctx).Debug("Comment not created")
 	}
 
 	return comment, err
 }
 
-func (c *Client) GetOrCreateIssueComment(owner, repo string, number int, body string) (*github.IssueComment, error) {
-	comment, err := c.GetIssueComment(owner, repo, number, body)
+func (c *Client) GetOrCreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	comment, err := c.GetIssueComment(ctx, owner, repo, number, body)
 	if err != nil {
 		return nil, err
 	}
 	if comment != nil {
 		return comment, nil
 	}
-	return c.CreateIssueComment(owner, repo, number, body)
+	return c.CreateIssueComment(ctx, owner, repo, number, body)
 }
 
-func (c *Client) GetBranch(owner, repo, name string) (*github.Branch, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetIssueComments(ctx context.Context, owner, repo string, number int) ([]*github.IssueComment, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+	}).Debug("Listing issue comments...")
+
+	opt := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var comments []*github.IssueComment
+	for {
+		cs, r, err := c.v3.Issues.ListComments(ctx, owner, repo, number, opt)
+		err = wrapError(err)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, cs...)
+		if r.NextPage == 0 {
+			break
+		}
+		opt.Page = r.NextPage
+	}
+	return comments, nil
+}
+
+func (c *Client) GetIssueCommentByID(ctx context.Context, owner, repo string, id int64) (*github.IssueComment, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"id":    id,
+	}).Debug("Searching for issue comment...")
+
+	comment, _, err := c.v3.Issues.GetComment(ctx, owner, repo, id)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return comment, err
+}
+
+func (c *Client) UpdateIssueComment(ctx context.Context, owner, repo string, id int64, body string) (*github.IssueComment, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"id":    id,
+		"body":  body,
+	}).Debug("Updating issue comment...")
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("update comment %d in %s/%s/%s", id, util.GitHubServerURL(), owner, repo), log.Fields{
+			"owner": owner,
+			"repo":  repo,
+			"id":    id,
+		})
+		return &github.IssueComment{
+			ID:   &id,
+			Body: &body,
+		}, nil
+	}
+
+	comment, _, err := c.v3.Issues.EditComment(ctx, owner, repo, id, &github.IssueComment{
+		Body: &body,
+	})
+	return comment, wrapError(err)
+}
+
+func (c *Client) DeleteIssueComment(ctx context.Context, owner, repo string, id int64) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"id":    id,
+	}).Debug("Deleting issue comment...")
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("delete comment %d from %s/%s/%s", id, util.GitHubServerURL(), owner, repo), log.Fields{
+			"owner": owner,
+			"repo":  repo,
+			"id":    id,
+		})
+		return nil
+	}
+
+	_, err := c.v3.Issues.DeleteComment(ctx, owner, repo, id)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) GetBranch(ctx context.Context, owner, repo, name string) (*github.Branch, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"name":  name,
 	}).Debug("Searching for branch...")
 
-	branch, _, err := c.v3.Repositories.GetBranch(context.Background(), owner, repo, name, false)
-	if err != nil && strings.Contains(err.Error(), "404") {
+	branch, _, err := c.v3.Repositories.GetBranch(ctx, owner, repo, name, false)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
 		return nil, nil
 	}
 
 	if branch != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"commit": branch.GetCommit().GetSHA(),
 		}).Debug("Found branch")
 	} else {
-		log.Debug("Branch not found")
+		util.Logger(ctx).Debug("Branch not found")
 	}
 
 	return branch, err
 }
 
-func (c *Client) CreateBranch(owner, repo, name, source string) (*github.Branch, error) {
-	log.WithFields(log.Fields{
+func (c *Client) CreateBranch(ctx context.Context, owner, repo, name, source string) (*github.Branch, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner":  owner,
 		"repo":   repo,
 		"name":   name,
 		"source": source,
 	}).Debug("Creating branch...")
 
-	r, _, err := c.v3.Git.GetRef(context.Background(), owner, repo, "refs/heads/"+source)
+	r, _, err := c.v3.Git.GetRef(ctx, owner, repo, "refs/heads/"+source)
+	err = wrapError(err)
 	if err != nil {
 		return nil, err
 	}
 
-	b, _, err := c.v3.Git.CreateRef(context.Background(), owner, repo, &github.Reference{
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("create branch %s in %s/%s/%s from %s", name, util.GitHubServerURL(), owner, repo, source), log.Fields{
+			"owner":  owner,
+			"repo":   repo,
+			"name":   name,
+			"source": source,
+		})
+		return &github.Branch{
+			Name: &name,
+			Commit: &github.RepositoryCommit{
+				SHA: r.GetObject().SHA,
+			},
+		}, nil
+	}
+
+	b, _, err := c.v3.Git.CreateRef(ctx, owner, repo, &github.Reference{
 		Ref:    github.String("refs/heads/" + name),
 		Object: r.GetObject(),
 	})
+	err = wrapError(err)
 	if err != nil {
 		return nil, err
 	}
 
 	if b != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": b.GetURL(),
 		}).Debug("Created branch")
+		c.record(journal.Artifact{
+			Kind:  journal.ArtifactBranch,
+			Owner: owner,
+			Repo:  repo,
+			Name:  name,
+			SHA:   b.GetObject().GetSHA(),
+			URL:   util.GitHubURL("%s/%s/tree/%s", owner, repo, name),
+		})
 	} else {
-		log.Debug("Branch not created")
+		util.Logger(ctx).Debug("Branch not created")
 	}
 
-	return c.GetBranch(owner, repo, name)
+	return c.GetBranch(ctx, owner, repo, name)
 }
 
-func (c *Client) GetOrCreateBranch(owner, repo, name, source string) (*github.Branch, error) {
-	branch, err := c.GetBranch(owner, repo, name)
+func (c *Client) GetOrCreateBranch(ctx context.Context, owner, repo, name, source string) (*github.Branch, error) {
+	branch, err := c.GetBranch(ctx, owner, repo, name)
 	if err != nil {
 		return nil, err
 	}
@@ -250,48 +515,84 @@ func (c *Client) GetOrCreateBranch(owner, repo, name, source string) (*github.Br
 		return branch, nil
 	}
 
-	return c.CreateBranch(owner, repo, name, source)
+	return c.CreateBranch(ctx, owner, repo, name, source)
 }
 
-func (c *Client) GetPR(owner, repo, head string) (*github.PullRequest, error) {
-	log.WithFields(log.Fields{
+// GetPR returns the newest open or merged PR from the head branch of the repository.
+// If all the PRs from the branch were closed without being merged, the newest of them is returned.
+// The PRs are listed rather than searched for, because the search index lags behind the PRs that were just created.
+func (c *Client) GetPR(ctx context.Context, owner, repo, head string) (*github.PullRequest, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"head":  head,
 	}).Debug("Searching for PR...")
 
-	q := fmt.Sprintf("is:pr repo:%s/%s head:%s", owner, repo, head)
-	r, _, err := c.v3.Search.Issues(context.Background(), q, &github.SearchOptions{
+	opt := &github.PullRequestListOptions{
+		State:       "all",
+		Head:        fmt.Sprintf("%s:%s", owner, head),
+		Sort:        "created",
+		Direction:   "desc",
 		ListOptions: github.ListOptions{PerPage: 100},
-	})
-	if err != nil {
-		return nil, err
-	}
-	if len(r.Issues) == 0 {
-		return nil, nil
 	}
-	for _, i := range r.Issues {
-		n := i.GetNumber()
-		pr, _, err := c.v3.PullRequests.Get(context.Background(), owner, repo, n)
+	var prs []*github.PullRequest
+	for {
+		ps, r, err := c.v3.PullRequests.List(ctx, owner, repo, opt)
+		err = wrapError(err)
 		if err != nil {
 			return nil, err
 		}
-		if pr != nil {
-			if pr.GetHead().GetRef() == head {
-				log.WithFields(log.Fields{
-					"url": pr.GetHTMLURL(),
-				}).Debug("Found PR")
-				return pr, nil
-			}
+		prs = append(prs, ps...)
+		if r.NextPage == 0 {
+			break
 		}
+		opt.Page = r.NextPage
 	}
 
-	log.Debug("PR not found")
-	return nil, nil
+	var found *github.PullRequest
+	for _, pr := range newestFirst(prs) {
+		if pr.GetHead().GetRef() != head {
+			continue
+		}
+		if pr.GetState() == "open" || pr.MergedAt != nil {
+			found = pr
+			break
+		}
+		if found == nil {
+			found = pr
+		}
+	}
+	if found == nil {
+		util.Logger(ctx).Debug("PR not found")
+		return nil, nil
+	}
+
+	// the listed PRs do not say whether they are merged or mergeable
+	pr, _, err := c.v3.PullRequests.Get(ctx, owner, repo, found.GetNumber())
+	err = wrapError(err)
+	if err != nil {
+		return nil, err
+	}
+	util.Logger(ctx).WithFields(log.Fields{
+		"url": pr.GetHTMLURL(),
+	}).Debug("Found PR")
+	return pr, nil
 }
 
-func (c *Client) CreatePR(owner, repo, head, base, title, body string, draft bool) (*github.PullRequest, error) {
-	log.WithFields(log.Fields{
+// newestFirst orders the PRs by creation time, and by number if they were created at the same time
+func newestFirst(prs []*github.PullRequest) []*github.PullRequest {
+	sort.SliceStable(prs, func(i, j int) bool {
+		ti, tj := prs[i].GetCreatedAt(), prs[j].GetCreatedAt()
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return prs[i].GetNumber() > prs[j].GetNumber()
+	})
+	return prs
+}
+
+func (c *Client) CreatePR(ctx context.Context, owner, repo, head, base, title, body string, draft bool) (*github.PullRequest, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"head":  head,
@@ -301,27 +602,65 @@ func (c *Client) CreatePR(owner, repo, head, base, title, body string, draft boo
 		"draft": draft,
 	}).Debug("Creating PR...")
 
-	pr, _, err := c.v3.PullRequests.Create(context.Background(), owner, repo, &github.NewPullRequest{
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("open PR '%s' from %s to %s in %s/%s/%s", title, head, base, util.GitHubServerURL(), owner, repo), log.Fields{
+			"owner": owner,
+			"repo":  repo,
+			"head":  head,
+			"base":  base,
+			"draft": draft,
+		})
+		return &github.PullRequest{
+			Title:   &title,
+			Body:    &body,
+			Draft:   &draft,
+			State:   github.String("open"),
+			HTMLURL: github.String(util.GitHubURL("%s/%s/compare/%s...%s", owner, repo, base, head)),
+			Head: &github.PullRequestBranch{
+				Ref: &head,
+			},
+			Base: &github.PullRequestBranch{
+				Ref: &base,
+				Repo: &github.Repository{
+					Name: &repo,
+					Owner: &github.User{
+						Login: &owner,
+					},
+				},
+			},
+		}, nil
+	}
+
+	pr, _, err := c.v3.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
 		Title: &title,
 		Head:  &head,
 		Base:  &base,
 		Body:  &body,
 		Draft: &draft,
 	})
+	err = wrapError(err)
 
 	if pr != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": pr.GetHTMLURL(),
 		}).Debug("Created PR")
+		c.record(journal.Artifact{
+			Kind:  journal.ArtifactPR,
+			Owner: owner,
+			Repo:  repo,
+			Name:  head,
+			ID:    int64(pr.GetNumber()),
+			URL:   pr.GetHTMLURL(),
+		})
 	} else {
-		log.Debug("PR not created")
+		util.Logger(ctx).Debug("PR not created")
 	}
 
 	return pr, err
 }
 
-func (c *Client) GetOrCreatePR(owner, repo, head, base, title, body string, draft bool) (*github.PullRequest, error) {
-	pr, err := c.GetPR(owner, repo, head)
+func (c *Client) GetOrCreatePR(ctx context.Context, owner, repo, head, base, title, body string, draft bool) (*github.PullRequest, error) {
+	pr, err := c.GetPR(ctx, owner, repo, head)
 	if err != nil {
 		return nil, err
 	}
@@ -329,12 +668,19 @@ func (c *Client) GetOrCreatePR(owner, repo, head, base, title, body string, draf
 		return pr, nil
 	}
 	if pr == nil || pr.GetState() == "closed" {
-		pr, err = c.CreatePR(owner, repo, head, base, title, body, draft)
+		pr, err = c.CreatePR(ctx, owner, repo, head, base, title, body, draft)
 		if err != nil {
 			return nil, err
 		}
 	}
 	if !draft && pr.GetDraft() {
+		if c.plan != nil {
+			c.plan.Add(fmt.Sprintf("mark %s as ready for review", pr.GetHTMLURL()), log.Fields{
+				"url": pr.GetHTMLURL(),
+			})
+			pr.Draft = &draft
+			return pr, nil
+		}
 		var m struct {
 			MarkPullRequestReadyForReview struct {
 				PullRequest struct {
@@ -345,7 +691,7 @@ func (c *Client) GetOrCreatePR(owner, repo, head, base, title, body string, draf
 		input := githubv4.MarkPullRequestReadyForReviewInput{
 			PullRequestID: pr.GetNodeID(),
 		}
-		err = c.v4.Mutate(context.Background(), &m, input, nil)
+		err = c.v4.Mutate(ctx, &m, input, nil)
 		if err != nil {
 			return pr, err
 		}
@@ -354,45 +700,284 @@ func (c *Client) GetOrCreatePR(owner, repo, head, base, title, body string, draf
 	return pr, nil
 }
 
-func (c *Client) UpdatePR(pr *github.PullRequest) error {
-	log.WithFields(log.Fields{
+// MergeMethod is how a PR gets merged once auto-merge kicks in
+type MergeMethod = githubv4.PullRequestMergeMethod
+
+const (
+	MergeMethodMerge  = githubv4.PullRequestMergeMethodMerge
+	MergeMethodSquash = githubv4.PullRequestMergeMethodSquash
+	MergeMethodRebase = githubv4.PullRequestMergeMethodRebase
+)
+
+// EnableAutoMerge makes GitHub merge the PR with the method as soon as its required checks pass.
+// It does nothing if auto-merge is enabled with the same method already.
+func (c *Client) EnableAutoMerge(ctx context.Context, pr *github.PullRequest, method MergeMethod) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"url":    pr.GetHTMLURL(),
+		"method": method,
+	}).Debug("Enabling auto-merge...")
+
+	if pr.AutoMerge != nil && strings.EqualFold(pr.AutoMerge.GetMergeMethod(), string(method)) {
+		util.Logger(ctx).Debug("Auto-merge is enabled already")
+		return nil
+	}
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("enable auto-merge (%s) on %s", strings.ToLower(string(method)), pr.GetHTMLURL()), log.Fields{
+			"url":    pr.GetHTMLURL(),
+			"method": method,
+		})
+		return nil
+	}
+
+	var m struct {
+		EnablePullRequestAutoMerge struct {
+			PullRequest struct {
+				ID githubv4.ID
+			}
+		} `graphql:"enablePullRequestAutoMerge(input: $input)"`
+	}
+	input := githubv4.EnablePullRequestAutoMergeInput{
+		PullRequestID: pr.GetNodeID(),
+		MergeMethod:   &method,
+	}
+	err := c.v4.Mutate(ctx, &m, input, nil)
+	if err != nil {
+		return err
+	}
+	pr.AutoMerge = &github.PullRequestAutoMerge{
+		MergeMethod: github.String(strings.ToLower(string(method))),
+	}
+	return nil
+}
+
+func (c *Client) UpdatePR(ctx context.Context, pr *github.PullRequest) error {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner":  pr.Base.Repo.Owner.GetLogin(),
 		"repo":   pr.Base.Repo.GetName(),
 		"number": pr.GetNumber(),
 	}).Debug("Updating PR...")
 
-	_, _, err := c.v3.PullRequests.Edit(context.Background(), pr.Base.Repo.Owner.GetLogin(), pr.Base.Repo.GetName(), pr.GetNumber(), pr)
-	return err
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("update %s", pr.GetHTMLURL()), log.Fields{
+			"url":   pr.GetHTMLURL(),
+			"title": pr.GetTitle(),
+			"body":  pr.GetBody(),
+		})
+		return nil
+	}
+
+	_, _, err := c.v3.PullRequests.Edit(ctx, pr.Base.Repo.Owner.GetLogin(), pr.Base.Repo.GetName(), pr.GetNumber(), pr)
+	return wrapError(err)
 }
 
-func (c *Client) GetFile(owner, repo, path, ref string) (*github.RepositoryContent, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetPRByNumber(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+	}).Debug("Searching for PR...")
+
+	pr, _, err := c.v3.PullRequests.Get(ctx, owner, repo, number)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return pr, err
+}
+
+// GetPRFiles returns the paths of the files that the PR touches
+func (c *Client) GetPRFiles(ctx context.Context, pr *github.PullRequest) ([]string, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"url": pr.GetHTMLURL(),
+	}).Debug("Searching for PR files...")
+
+	opt := &github.ListOptions{PerPage: 100}
+	var files []string
+	for {
+		fs, r, err := c.v3.PullRequests.ListFiles(ctx, pr.Base.Repo.Owner.GetLogin(), pr.Base.Repo.GetName(), pr.GetNumber(), opt)
+		err = wrapError(err)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fs {
+			files = append(files, f.GetFilename())
+		}
+		if r.NextPage == 0 {
+			break
+		}
+		opt.Page = r.NextPage
+	}
+	return files, nil
+}
+
+// RequestReviewers requests reviews from the users and the teams of the base repository's owner.
+// The ones who were requested or who reviewed the PR already are skipped, so that reruns do not bother them again.
+func (c *Client) RequestReviewers(ctx context.Context, pr *github.PullRequest, reviewers, teams []string) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"url":       pr.GetHTMLURL(),
+		"reviewers": reviewers,
+		"teams":     teams,
+	}).Debug("Requesting reviewers...")
+
+	owner := pr.Base.Repo.Owner.GetLogin()
+	repo := pr.Base.Repo.GetName()
+
+	done := make(map[string]bool)
+	for _, u := range pr.RequestedReviewers {
+		done[strings.ToLower(u.GetLogin())] = true
+	}
+	for _, t := range pr.RequestedTeams {
+		done["@"+strings.ToLower(t.GetSlug())] = true
+	}
+	if pr.GetNumber() != 0 {
+		opt := &github.ListOptions{PerPage: 100}
+		for {
+			rs, r, err := c.v3.PullRequests.ListReviews(ctx, owner, repo, pr.GetNumber(), opt)
+			err = wrapError(err)
+			if err != nil {
+				return err
+			}
+			for _, review := range rs {
+				done[strings.ToLower(review.GetUser().GetLogin())] = true
+			}
+			if r.NextPage == 0 {
+				break
+			}
+			opt.Page = r.NextPage
+		}
+	}
+
+	request := github.ReviewersRequest{}
+	for _, r := range reviewers {
+		if !done[strings.ToLower(r)] {
+			request.Reviewers = append(request.Reviewers, r)
+		}
+	}
+	for _, t := range teams {
+		if !done["@"+strings.ToLower(t)] {
+			request.TeamReviewers = append(request.TeamReviewers, t)
+		}
+	}
+	if len(request.Reviewers) == 0 && len(request.TeamReviewers) == 0 {
+		util.Logger(ctx).Debug("All the reviewers were requested already")
+		return nil
+	}
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("request reviews from %s on %s", strings.Join(append(request.Reviewers, request.TeamReviewers...), ", "), pr.GetHTMLURL()), log.Fields{
+			"url":       pr.GetHTMLURL(),
+			"reviewers": request.Reviewers,
+			"teams":     request.TeamReviewers,
+		})
+		return nil
+	}
+
+	_, _, err := c.v3.PullRequests.RequestReviewers(ctx, owner, repo, pr.GetNumber(), request)
+	return wrapError(err)
+}
+
+// AddAssignees assigns the users to the PR, skipping the ones who are assigned already
+func (c *Client) AddAssignees(ctx context.Context, pr *github.PullRequest, assignees []string) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"url":       pr.GetHTMLURL(),
+		"assignees": assignees,
+	}).Debug("Adding assignees...")
+
+	assigned := make(map[string]bool)
+	for _, u := range pr.Assignees {
+		assigned[strings.ToLower(u.GetLogin())] = true
+	}
+	var missing []string
+	for _, a := range assignees {
+		if !assigned[strings.ToLower(a)] {
+			missing = append(missing, a)
+		}
+	}
+	if len(missing) == 0 {
+		util.Logger(ctx).Debug("All the assignees were assigned already")
+		return nil
+	}
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("assign %s to %s", strings.Join(missing, ", "), pr.GetHTMLURL()), log.Fields{
+			"url":       pr.GetHTMLURL(),
+			"assignees": missing,
+		})
+		return nil
+	}
+
+	_, _, err := c.v3.Issues.AddAssignees(ctx, pr.Base.Repo.Owner.GetLogin(), pr.Base.Repo.GetName(), pr.GetNumber(), missing)
+	return wrapError(err)
+}
+
+func (c *Client) ClosePR(ctx context.Context, pr *github.PullRequest) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"url": pr.GetHTMLURL(),
+	}).Debug("Closing PR...")
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("close %s", pr.GetHTMLURL()), log.Fields{
+			"url": pr.GetHTMLURL(),
+		})
+		return nil
+	}
+
+	_, _, err := c.v3.PullRequests.Edit(ctx, pr.Base.Repo.Owner.GetLogin(), pr.Base.Repo.GetName(), pr.GetNumber(), &github.PullRequest{
+		State: github.String("closed"),
+	})
+	return wrapError(err)
+}
+
+func (c *Client) DeleteBranch(ctx context.Context, owner, repo, name string) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"name":  name,
+	}).Debug("Deleting branch...")
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("delete branch %s/%s/%s/tree/%s", util.GitHubServerURL(), owner, repo, name), log.Fields{
+			"owner": owner,
+			"repo":  repo,
+			"name":  name,
+		})
+		return nil
+	}
+
+	_, err := c.v3.Git.DeleteRef(ctx, owner, repo, fmt.Sprintf("heads/%s", name))
+	return wrapError(err)
+}
+
+func (c *Client) GetFile(ctx context.Context, owner, repo, path, ref string) (*github.RepositoryContent, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"path":  path,
 		"ref":   ref,
 	}).Debug("Searching for file...")
 
-	f, _, _, err := c.v3.Repositories.GetContents(context.Background(), owner, repo, path, &github.RepositoryContentGetOptions{
+	f, _, _, err := c.v3.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{
 		Ref: ref,
 	})
+	err = wrapError(err)
 
 	if f != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": f.GetHTMLURL(),
 		}).Debug("Found file")
 	} else {
-		log.Debug("File not found")
+		util.Logger(ctx).Debug("File not found")
 	}
 
-	if err != nil && strings.Contains(err.Error(), "404") {
+	if errors.Is(err, ErrNotFound) {
 		return nil, nil
 	}
 	return f, err
 }
 
-func (c *Client) GetCheckRuns(owner, repo, ref string) ([]*github.CheckRun, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"ref":   ref,
@@ -403,7 +988,8 @@ func (c *Client) GetCheckRuns(owner, repo, ref string) ([]*github.CheckRun, erro
 	}
 	var runs []*github.CheckRun
 	for {
-		rs, r, err := c.v3.Checks.ListCheckRunsForRef(context.Background(), owner, repo, ref, opt)
+		rs, r, err := c.v3.Checks.ListCheckRunsForRef(ctx, owner, repo, ref, opt)
+		err = wrapError(err)
 		if err != nil {
 			return nil, err
 		}
@@ -419,24 +1005,24 @@ func (c *Client) GetCheckRuns(owner, repo, ref string) ([]*github.CheckRun, erro
 		for _, r := range runs {
 			urls = append(urls, r.GetHTMLURL())
 		}
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"urls": urls,
 		}).Debug("Found check runs")
 	} else {
-		log.Debug("Check runs not found")
+		util.Logger(ctx).Debug("Check runs not found")
 	}
 
 	return runs, nil
 }
 
-func (c *Client) GetIncompleteCheckRuns(owner, repo, ref string) ([]*github.CheckRun, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetIncompleteCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"ref":   ref,
 	}).Debug("Searching for incomplete check runs...")
 
-	runs, err := c.GetCheckRuns(owner, repo, ref)
+	runs, err := c.GetCheckRuns(ctx, owner, repo, ref)
 	if err != nil {
 		return nil, err
 	}
@@ -453,24 +1039,24 @@ func (c *Client) GetIncompleteCheckRuns(owner, repo, ref string) ([]*github.Chec
 		for _, r := range incomplete {
 			urls = append(urls, r.GetHTMLURL())
 		}
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"urls": urls,
 		}).Debug("Found incomplete check runs")
 	} else {
-		log.Debug("Incomplete check runs not found")
+		util.Logger(ctx).Debug("Incomplete check runs not found")
 	}
 
 	return incomplete, nil
 }
 
-func (c *Client) GetUnsuccessfulCheckRuns(owner, repo, ref string) ([]*github.CheckRun, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetUnsuccessfulCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"ref":   ref,
 	}).Debug("Searching for unsuccessful check runs...")
 
-	runs, err := c.GetCheckRuns(owner, repo, ref)
+	runs, err := c.GetCheckRuns(ctx, owner, repo, ref)
 	if err != nil {
 		return nil, err
 	}
@@ -487,23 +1073,213 @@ func (c *Client) GetUnsuccessfulCheckRuns(owner, repo, ref string) ([]*github.Ch
 		for _, r := range unsuccessful {
 			urls = append(urls, r.GetHTMLURL())
 		}
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"urls": urls,
 		}).Debug("Found unsuccessful check runs")
 	} else {
-		log.Debug("Unsuccessful check runs not found")
+		util.Logger(ctx).Debug("Unsuccessful check runs not found")
 	}
 
 	return unsuccessful, nil
 }
 
+// GetRequiredStatusChecks returns the contexts of the checks that the branch protection and the rulesets require on the branch
+func (c *Client) GetRequiredStatusChecks(ctx context.Context, owner, repo, branch string) ([]string, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"branch": branch,
+	}).Debug("Searching for required status checks...")
+
+	var required []string
+	seen := make(map[string]bool)
+	add := func(context string) {
+		if context != "" && !seen[context] {
+			seen[context] = true
+			required = append(required, context)
+		}
+	}
+
+	// go-github does not decode the protection summary of a branch, which unlike the protection endpoint does not need admin access
+	req, err := c.v3.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/branches/%s", owner, repo, branch), nil)
+	if err != nil {
+		return nil, err
+	}
+	var b struct {
+		Protection struct {
+			RequiredStatusChecks *github.RequiredStatusChecks `json:"required_status_checks"`
+		} `json:"protection"`
+	}
+	_, err = c.v3.Do(ctx, req, &b)
+	err = wrapError(err)
+	if err != nil {
+		return nil, err
+	}
+	if checks := b.Protection.RequiredStatusChecks; checks != nil {
+		for _, context := range checks.Contexts {
+			add(context)
+		}
+		for _, check := range checks.Checks {
+			add(check.Context)
+		}
+	}
+
+	// nor does it know about the rulesets
+	req, err = c.v3.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/rules/branches/%s?per_page=100", owner, repo, branch), nil)
+	if err != nil {
+		return nil, err
+	}
+	var rules []struct {
+		Type       string `json:"type"`
+		Parameters struct {
+			RequiredStatusChecks []github.RequiredStatusCheck `json:"required_status_checks"`
+		} `json:"parameters"`
+	}
+	_, err = c.v3.Do(ctx, req, &rules)
+	err = wrapError(err)
+	// the GitHub Enterprise Server versions without rulesets do not have the endpoint
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.Type == "required_status_checks" {
+			for _, check := range rule.Parameters.RequiredStatusChecks {
+				add(check.Context)
+			}
+		}
+	}
+
+	util.Logger(ctx).WithFields(log.Fields{
+		"required": required,
+	}).Debug("Found required status checks")
+
+	return required, nil
+}
+
+// GetCommitStatuses returns the latest status of every context that reported through the legacy commit statuses API
+func (c *Client) GetCommitStatuses(ctx context.Context, owner, repo, ref string) ([]*github.RepoStatus, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"ref":   ref,
+	}).Debug("Searching for commit statuses...")
+
+	opt := &github.ListOptions{PerPage: 100}
+	var statuses []*github.RepoStatus
+	for {
+		combined, r, err := c.v3.Repositories.GetCombinedStatus(ctx, owner, repo, ref, opt)
+		err = wrapError(err)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, combined.Statuses...)
+		if r.NextPage == 0 {
+			break
+		}
+		opt.Page = r.NextPage
+	}
+
+	util.Logger(ctx).WithFields(log.Fields{
+		"statuses": len(statuses),
+	}).Debug("Found commit statuses")
+
+	return statuses, nil
+}
+
 type WorkflowRunInput struct {
 	Name  string
 	Value interface{}
 }
 
-func (c *Client) CreateWorkflowRun(owner, repo, file, ref string, inputs ...WorkflowRunInput) error {
-	log.WithFields(log.Fields{
+// WorkflowRunCorrelationInput is passed to the dispatched workflows which declare it, so that they can put it in their run-name
+const WorkflowRunCorrelationInput = "kuboreleaser_id"
+
+// workflowInputs caches whether the workflows declare the correlation input, so that each workflow file is read only once
+type workflowInputs struct {
+	mu       sync.Mutex
+	declared map[string]bool
+}
+
+// acceptsCorrelationInput reports whether the workflow declares the correlation input on the ref, GitHub rejects the inputs a workflow does not declare
+func (c *Client) acceptsCorrelationInput(ctx context.Context, owner, repo, file, ref string) (bool, error) {
+	key := fmt.Sprintf("%s/%s/%s@%s", owner, repo, file, ref)
+	c.workflows.mu.Lock()
+	defer c.workflows.mu.Unlock()
+	if declared, ok := c.workflows.declared[key]; ok {
+		return declared, nil
+	}
+
+	f, err := c.GetFile(ctx, owner, repo, ".github/workflows/"+file, ref)
+	if err != nil {
+		return false, err
+	}
+	declared := false
+	if f != nil {
+		content, err := f.GetContent()
+		if err != nil {
+			return false, err
+		}
+		// the inputs are the keys under on.workflow_dispatch.inputs, nothing else in a workflow is named like the correlation input
+		declared = strings.Contains(content, WorkflowRunCorrelationInput+":")
+	}
+	c.workflows.declared[key] = declared
+	return declared, nil
+}
+
+// the created_at of a run is truncated to the second and the clocks of the API servers drift a little
+const dispatchSlack = 5 * time.Second
+
+// WorkflowRunHandle identifies the run triggered by a workflow_dispatch event.
+// GitHub does not return the run it starts, so it is found among the runs of the workflow that the same actor dispatched on the same ref
+// right after DispatchedAt, preferring the one whose title contains the correlation ID.
+type WorkflowRunHandle struct {
+	Owner        string
+	Repo         string
+	File         string
+	Ref          string
+	Actor        string
+	DispatchedAt time.Time
+	// CorrelationID is empty if the workflow does not accept the correlation input
+	CorrelationID string
+	// ID is 0 until the run is found
+	ID  int64
+	URL string
+}
+
+// NewWorkflowRunHandle restores the handle of a run from its journal artifact
+func NewWorkflowRunHandle(artifact journal.Artifact) *WorkflowRunHandle {
+	return &WorkflowRunHandle{
+		Owner:         artifact.Owner,
+		Repo:          artifact.Repo,
+		File:          artifact.Name,
+		Ref:           artifact.Ref,
+		Actor:         artifact.Actor,
+		DispatchedAt:  artifact.Time,
+		CorrelationID: artifact.CorrelationID,
+		ID:            artifact.ID,
+		URL:           artifact.URL,
+	}
+}
+
+func (h *WorkflowRunHandle) artifact() journal.Artifact {
+	return journal.Artifact{
+		Kind:          journal.ArtifactWorkflowRun,
+		Owner:         h.Owner,
+		Repo:          h.Repo,
+		Name:          h.File,
+		ID:            h.ID,
+		Ref:           h.Ref,
+		Actor:         h.Actor,
+		CorrelationID: h.CorrelationID,
+		URL:           h.URL,
+		Time:          h.DispatchedAt,
+	}
+}
+
+// CreateWorkflowRun dispatches the workflow and returns the handle of the run it triggered.
+// The run is looked for a few times, if it does not show up in time the handle is returned without an ID and FindWorkflowRun finds it later.
+func (c *Client) CreateWorkflowRun(ctx context.Context, owner, repo, file, ref string, inputs ...WorkflowRunInput) (*WorkflowRunHandle, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner":  owner,
 		"repo":   repo,
 		"file":   file,
@@ -511,27 +1287,160 @@ func (c *Client) CreateWorkflowRun(owner, repo, file, ref string, inputs ...Work
 		"inputs": inputs,
 	}).Debug("Creating workflow run...")
 
+	handle := &WorkflowRunHandle{
+		Owner:         owner,
+		Repo:          repo,
+		File:          file,
+		Ref:           ref,
+		DispatchedAt:  time.Now(),
+		CorrelationID: strconv.FormatInt(time.Now().UnixNano(), 36),
+		URL:           util.GitHubURL("%s/%s/actions/workflows/%s", owner, repo, file),
+	}
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("run workflow %s on %s in %s/%s/%s", file, ref, util.GitHubServerURL(), owner, repo), log.Fields{
+			"owner":  owner,
+			"repo":   repo,
+			"file":   file,
+			"ref":    ref,
+			"inputs": inputs,
+		})
+		return handle, nil
+	}
+
+	actor, err := c.GetLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	handle.Actor = actor
+
 	is := make(map[string]interface{})
 	for _, i := range inputs {
 		is[i.Name] = i.Value
 	}
+	// most workflows do not declare the correlation input, their runs are told apart by the actor and the time of the dispatch
+	accepts, err := c.acceptsCorrelationInput(ctx, owner, repo, file, ref)
+	if err != nil {
+		return nil, err
+	}
+	if accepts {
+		is[WorkflowRunCorrelationInput] = handle.CorrelationID
+	} else {
+		util.Logger(ctx).WithField("file", file).Debug("The workflow does not accept the correlation input, dispatching without it...")
+		handle.CorrelationID = ""
+	}
 
-	_, err := c.v3.Actions.CreateWorkflowDispatchEventByFileName(context.Background(), owner, repo, file, github.CreateWorkflowDispatchEventRequest{
+	r, err := c.v3.Actions.CreateWorkflowDispatchEventByFileName(ctx, owner, repo, file, github.CreateWorkflowDispatchEventRequest{
 		Ref:    ref,
 		Inputs: is,
 	})
+	err = wrapError(err)
+	if err != nil {
+		util.Logger(ctx).Debug("Failed to create workflow run")
+		return nil, err
+	}
 
+	// the clock of GitHub decides which runs were created after the dispatch
+	if date, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+		handle.DispatchedAt = date
+	}
+	util.Logger(ctx).Debug("Created workflow run")
+	c.record(handle.artifact())
+
+	for attempt := 0; attempt < 5; attempt++ {
+		err = sleep(ctx, 2*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		run, err := c.FindWorkflowRun(ctx, handle)
+		if err != nil {
+			return nil, err
+		}
+		if run != nil {
+			return handle, nil
+		}
+	}
+	util.Logger(ctx).WithField("file", file).Debug("The workflow run did not show up yet")
+	return handle, nil
+}
+
+// workflowRun adds the fields which go-github does not know about yet
+type workflowRun struct {
+	*github.WorkflowRun
+	DisplayTitle string `json:"display_title"`
+}
+
+// FindWorkflowRun returns the run the handle identifies, or nil if it has not shown up yet.
+// Once found, the ID of the run is set on the handle and recorded.
+func (c *Client) FindWorkflowRun(ctx context.Context, handle *WorkflowRunHandle) (*github.WorkflowRun, error) {
+	if handle.ID != 0 {
+		return c.GetWorkflowRunByID(ctx, handle.Owner, handle.Repo, handle.ID)
+	}
+
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner":          handle.Owner,
+		"repo":           handle.Repo,
+		"file":           handle.File,
+		"ref":            handle.Ref,
+		"actor":          handle.Actor,
+		"dispatched_at":  handle.DispatchedAt,
+		"correlation_id": handle.CorrelationID,
+	}).Debug("Searching for dispatched workflow run...")
+
+	// go-github does not decode the display_title which carries the correlation ID, so the runs are listed by hand
+	query := url.Values{
+		"branch":   {handle.Ref},
+		"event":    {"workflow_dispatch"},
+		"created":  {">=" + handle.DispatchedAt.Add(-dispatchSlack).UTC().Format(time.RFC3339)},
+		"per_page": {"100"},
+	}
+	if handle.Actor != "" {
+		query.Set("actor", handle.Actor)
+	}
+	req, err := c.v3.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/actions/workflows/%s/runs?%s", handle.Owner, handle.Repo, url.PathEscape(handle.File), query.Encode()), nil)
 	if err != nil {
-		log.Debug("Failed to create workflow run")
-	} else {
-		log.Debug("Created workflow run")
+		return nil, err
+	}
+	var runs struct {
+		WorkflowRuns []workflowRun `json:"workflow_runs"`
+	}
+	_, err = c.v3.Do(ctx, req, &runs)
+	err = wrapError(err)
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	// the runs come newest first, the one triggered by the dispatch is the oldest unless the correlation ID says otherwise
+	var found *github.WorkflowRun
+	for i := len(runs.WorkflowRuns) - 1; i >= 0; i-- {
+		run := runs.WorkflowRuns[i]
+		if run.WorkflowRun == nil {
+			continue
+		}
+		if handle.CorrelationID != "" && strings.Contains(run.DisplayTitle, handle.CorrelationID) {
+			found = run.WorkflowRun
+			break
+		}
+		if found == nil {
+			found = run.WorkflowRun
+		}
+	}
+	if found == nil {
+		util.Logger(ctx).Debug("Dispatched workflow run not found")
+		return nil, nil
+	}
+
+	util.Logger(ctx).WithFields(log.Fields{
+		"url": found.GetHTMLURL(),
+	}).Debug("Found dispatched workflow run")
+	handle.ID = found.GetID()
+	handle.URL = found.GetHTMLURL()
+	c.record(handle.artifact())
+	return found, nil
 }
 
-func (c *Client) GetWorkflowRun(owner, repo, branch, file string, completed bool) (*github.WorkflowRun, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetWorkflowRun(ctx context.Context, owner, repo, branch, file string, completed bool) (*github.WorkflowRun, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner":     owner,
 		"repo":      repo,
 		"branch":    branch,
@@ -546,169 +1455,135 @@ func (c *Client) GetWorkflowRun(owner, repo, branch, file string, completed bool
 	if completed {
 		opt.Status = "completed"
 	}
-	r, _, err := c.v3.Actions.ListWorkflowRunsByFileName(context.Background(), owner, repo, file, opt)
+	r, _, err := c.v3.Actions.ListWorkflowRunsByFileName(ctx, owner, repo, file, opt)
+	err = wrapError(err)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(r.WorkflowRuns) > 0 {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": r.WorkflowRuns[0].GetHTMLURL(),
 		}).Debug("Found workflow run")
 		return r.WorkflowRuns[0], nil
 	} else {
-		log.Debug("Workflow run not found")
+		util.Logger(ctx).Debug("Workflow run not found")
 		return nil, nil
 	}
 }
 
-type WorkflowRunJobStepLogs struct {
-	Name    string
-	RawLogs string
-}
-
-type WorkflowRunJobLogs struct {
-	Name        string
-	RawLogs     string
-	JobStepLogs map[string]*WorkflowRunJobStepLogs
-}
-
-type WorkflowRunLogs struct {
-	JobLogs map[string]*WorkflowRunJobLogs
-}
-
-func (c *Client) GetWorkflowRunLogs(owner, repo string, id int64) (*WorkflowRunLogs, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetWorkflowRunByID(ctx context.Context, owner, repo string, id int64) (*github.WorkflowRun, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"id":    id,
-	}).Debug("Searching for workflow run logs...")
+	}).Debug("Searching for workflow run...")
 
-	url, _, err := c.v3.Actions.GetWorkflowRunLogs(context.Background(), owner, repo, id, true)
-	if err != nil {
-		return nil, err
+	run, _, err := c.v3.Actions.GetWorkflowRunByID(ctx, owner, repo, id)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
 	}
+	return run, err
+}
 
-	r, err := http.Get(url.String())
-	if err != nil {
-		return nil, err
+// RerunFailedJobs starts a new attempt of the run which reruns its failed jobs and the jobs that depend on them
+func (c *Client) RerunFailedJobs(ctx context.Context, owner, repo string, id int64) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"id":    id,
+	}).Debug("Rerunning failed jobs...")
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("rerun the failed jobs of %s/%s/%s/actions/runs/%d", util.GitHubServerURL(), owner, repo, id), log.Fields{
+			"owner": owner,
+			"repo":  repo,
+			"id":    id,
+		})
+		return nil
 	}
-	defer r.Body.Close()
 
-	body, err := io.ReadAll(r.Body)
+	_, err := c.v3.Actions.RerunFailedJobsByID(ctx, owner, repo, id)
+	err = wrapError(err)
 	if err != nil {
-		return nil, err
+		util.Logger(ctx).Debug("Failed to rerun failed jobs")
+	} else {
+		util.Logger(ctx).Debug("Reran failed jobs")
 	}
+	return err
+}
 
-	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
-	if err != nil {
-		return nil, err
-	}
+// CountWorkflowRuns returns the number of the workflow runs, of any workflow, triggered on the ref
+func (c *Client) CountWorkflowRuns(ctx context.Context, owner, repo, ref string) (int, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"ref":   ref,
+	}).Debug("Counting workflow runs...")
 
-	logs := &WorkflowRunLogs{
-		JobLogs: make(map[string]*WorkflowRunJobLogs),
-	}
-	sort.Slice(reader.File, func(i, j int) bool {
-		return reader.File[i].Name < reader.File[j].Name
+	runs, _, err := c.v3.Actions.ListRepositoryWorkflowRuns(ctx, owner, repo, &github.ListWorkflowRunsOptions{
+		Branch: ref,
+		ListOptions: github.ListOptions{
+			PerPage: 1,
+		},
 	})
-	for _, f := range reader.File {
-		if !f.FileInfo().IsDir() {
-			name := strings.TrimSuffix(f.Name, ".txt")
-			if strings.HasSuffix(name, ")") {
-				name = name[:strings.LastIndex(name, "(")-1]
-			}
-			c, err := f.Open()
-			if err != nil {
-				return nil, err
-			}
-			defer c.Close()
-			b, err := io.ReadAll(c)
-			if err != nil {
-				return nil, err
-			}
-			job, step, _ := strings.Cut(name, "/")
-			if step == "" {
-				_, job, _ = strings.Cut(job, "_")
-			} else {
-				_, step, _ = strings.Cut(step, "_")
-			}
-			j := logs.JobLogs[job]
-			if j == nil {
-				j = &WorkflowRunJobLogs{
-					Name:        job,
-					JobStepLogs: make(map[string]*WorkflowRunJobStepLogs),
-				}
-				logs.JobLogs[job] = j
-			}
-			if step == "" {
-				j.RawLogs += string(b)
-			} else {
-				s := j.JobStepLogs[step]
-				if s == nil {
-					s = &WorkflowRunJobStepLogs{
-						Name: step,
-					}
-					j.JobStepLogs[step] = s
-				}
-				s.RawLogs += string(b)
-			}
-		}
+	err = wrapError(err)
+	if err != nil {
+		return 0, err
 	}
-
-	log.WithFields(log.Fields{
-		"url": url,
-	}).Debug("Got workflow run logs")
-
-	return logs, nil
+	return runs.GetTotalCount(), nil
 }
 
-func (c *Client) GetLatestRelease(owner, repo string) (*github.RepositoryRelease, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 	}).Debug("Searching for latest release...")
 
-	r, _, err := c.v3.Repositories.GetLatestRelease(context.Background(), owner, repo)
-	if err != nil && strings.Contains(err.Error(), "404") {
+	r, _, err := c.v3.Repositories.GetLatestRelease(ctx, owner, repo)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
 		return nil, nil
 	}
 
 	if r != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": r.GetHTMLURL(),
 		}).Debug("Found latest release")
 	} else {
-		log.Debug("Latest release not found")
+		util.Logger(ctx).Debug("Latest release not found")
 	}
 
 	return r, err
 }
 
-func (c *Client) GetRelease(owner, repo, tag string) (*github.RepositoryRelease, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetRelease(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"tag":   tag,
 	}).Debug("Searching for release...")
 
-	r, _, err := c.v3.Repositories.GetReleaseByTag(context.Background(), owner, repo, tag)
-	if err != nil && strings.Contains(err.Error(), "404") {
+	r, _, err := c.v3.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
 		return nil, nil
 	}
 
 	if r != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": r.GetHTMLURL(),
 		}).Debug("Found release")
 	} else {
-		log.Debug("Release not found")
+		util.Logger(ctx).Debug("Release not found")
 	}
 
 	return r, err
 }
 
-func (c *Client) CreateRelease(owner, repo, tag, name, body string, prerelease bool, latest bool) (*github.RepositoryRelease, error) {
-	log.WithFields(log.Fields{
+func (c *Client) CreateRelease(ctx context.Context, owner, repo, tag, name, body string, prerelease bool, latest bool) (*github.RepositoryRelease, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner":      owner,
 		"repo":       repo,
 		"tag":        tag,
@@ -718,74 +1593,138 @@ func (c *Client) CreateRelease(owner, repo, tag, name, body string, prerelease b
 		"latest":     latest,
 	}).Debug("Creating release...")
 
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("create release %s in %s/%s/%s", tag, util.GitHubServerURL(), owner, repo), log.Fields{
+			"owner":      owner,
+			"repo":       repo,
+			"tag":        tag,
+			"prerelease": prerelease,
+			"latest":     latest,
+		})
+		return &github.RepositoryRelease{
+			TagName:    &tag,
+			Name:       &name,
+			Body:       &body,
+			Prerelease: &prerelease,
+			HTMLURL:    github.String(util.GitHubURL("%s/%s/releases/tag/%s", owner, repo, tag)),
+		}, nil
+	}
+
 	makeLatest := "false"
 	if latest {
 		makeLatest = "true"
 	}
 
-	r, _, err := c.v3.Repositories.CreateRelease(context.Background(), owner, repo, &github.RepositoryRelease{
+	r, _, err := c.v3.Repositories.CreateRelease(ctx, owner, repo, &github.RepositoryRelease{
 		TagName:    &tag,
 		Name:       &name,
 		Body:       &body,
 		Prerelease: &prerelease,
 		MakeLatest: &makeLatest,
 	})
+	err = wrapError(err)
 
 	if r != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": r.GetHTMLURL(),
 		}).Debug("Created release")
+		c.record(journal.Artifact{
+			Kind:  journal.ArtifactRelease,
+			Owner: owner,
+			Repo:  repo,
+			Name:  tag,
+			ID:    r.GetID(),
+			URL:   r.GetHTMLURL(),
+		})
 	} else {
-		log.Debug("Release not created")
+		util.Logger(ctx).Debug("Release not created")
 	}
 
 	return r, err
 }
 
-func (c *Client) GetOrCreateRelease(owner, repo, tag, name, body string, prerelease bool, latest bool) (*github.RepositoryRelease, error) {
-	r, err := c.GetRelease(owner, repo, tag)
+func (c *Client) GetOrCreateRelease(ctx context.Context, owner, repo, tag, name, body string, prerelease bool, latest bool) (*github.RepositoryRelease, error) {
+	r, err := c.GetRelease(ctx, owner, repo, tag)
 	if err != nil {
 		return nil, err
 	}
 	if r == nil {
-		return c.CreateRelease(owner, repo, tag, name, body, prerelease, latest)
+		return c.CreateRelease(ctx, owner, repo, tag, name, body, prerelease, latest)
 	}
 	return r, nil
 }
 
-func (c *Client) GetTag(owner, repo, tag string) (*github.Tag, error) {
-	log.WithFields(log.Fields{
+func (c *Client) DeleteRelease(ctx context.Context, owner, repo string, release *github.RepositoryRelease) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"url": release.GetHTMLURL(),
+	}).Debug("Deleting release...")
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("delete release %s", release.GetHTMLURL()), log.Fields{
+			"url": release.GetHTMLURL(),
+		})
+		return nil
+	}
+
+	_, err := c.v3.Repositories.DeleteRelease(ctx, owner, repo, release.GetID())
+	return wrapError(err)
+}
+
+func (c *Client) GetTag(ctx context.Context, owner, repo, tag string) (*github.Tag, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"tag":   tag,
 	}).Debug("Searching for tag...")
 
-	r, _, err := c.v3.Git.GetRef(context.Background(), owner, repo, fmt.Sprintf("tags/%s", tag))
+	r, _, err := c.v3.Git.GetRef(ctx, owner, repo, fmt.Sprintf("tags/%s", tag))
+	err = wrapError(err)
 	if err != nil {
-		if strings.Contains(err.Error(), "404") {
+		if errors.Is(err, ErrNotFound) {
 			return nil, nil
 		}
 		return nil, err
 	}
 
-	t, _, err := c.v3.Git.GetTag(context.Background(), owner, repo, r.Object.GetSHA())
-	if err != nil && strings.Contains(err.Error(), "404") {
+	t, _, err := c.v3.Git.GetTag(ctx, owner, repo, r.Object.GetSHA())
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
 		return nil, nil
 	}
 
 	if t != nil {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"url": t.GetURL(),
 		}).Debug("Found tag")
 	} else {
-		log.Debug("Tag not found")
+		util.Logger(ctx).Debug("Tag not found")
 	}
 
 	return t, err
 }
 
-func (c *Client) Compare(owner, repo, base, head string) ([]*github.RepositoryCommit, error) {
-	log.WithFields(log.Fields{
+func (c *Client) DeleteTag(ctx context.Context, owner, repo, tag string) error {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"tag":   tag,
+	}).Debug("Deleting tag...")
+
+	if c.plan != nil {
+		c.plan.Add(fmt.Sprintf("delete tag %s from %s/%s/%s", tag, util.GitHubServerURL(), owner, repo), log.Fields{
+			"owner": owner,
+			"repo":  repo,
+			"tag":   tag,
+		})
+		return nil
+	}
+
+	_, err := c.v3.Git.DeleteRef(ctx, owner, repo, fmt.Sprintf("tags/%s", tag))
+	return wrapError(err)
+}
+
+func (c *Client) Compare(ctx context.Context, owner, repo, base, head string) ([]*github.RepositoryCommit, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"owner": owner,
 		"repo":  repo,
 		"base":  base,
@@ -795,7 +1734,8 @@ func (c *Client) Compare(owner, repo, base, head string) ([]*github.RepositoryCo
 	opts := &github.ListOptions{PerPage: 100}
 	var commits []*github.RepositoryCommit
 	for {
-		cs, r, err := c.v3.Repositories.CompareCommits(context.Background(), owner, repo, base, head, opts)
+		cs, r, err := c.v3.Repositories.CompareCommits(ctx, owner, repo, base, head, opts)
+		err = wrapError(err)
 		if err != nil {
 			return nil, err
 		}
@@ -807,11 +1747,11 @@ func (c *Client) Compare(owner, repo, base, head string) ([]*github.RepositoryCo
 	}
 
 	if len(commits) > 0 {
-		log.WithFields(log.Fields{
+		util.Logger(ctx).WithFields(log.Fields{
 			"commits": len(commits),
 		}).Debug("Found commits")
 	} else {
-		log.Debug("Commits not found")
+		util.Logger(ctx).Debug("Commits not found")
 	}
 
 	return commits, nil
@@ -0,0 +1,1017 @@
+// Package githubtest provides an in-memory GitHub which the actions can be run against without network access.
+package githubtest
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	kgithub "github.com/ipfs/kuboreleaser/github"
+	"github.com/ipfs/kuboreleaser/util"
+)
+
+type repoKey struct {
+	owner string
+	repo  string
+}
+
+// checkRun progresses from queued through in_progress to completed with the conclusion, one step per read
+type checkRun struct {
+	run        *github.CheckRun
+	conclusion string
+	// pinned check runs keep their status when they are read
+	pinned bool
+}
+
+type repository struct {
+	branches     map[string]string
+	tags         map[string]*github.Tag
+	files        map[string]map[string]string
+	issues       []*github.Issue
+	comments     map[int][]*github.IssueComment
+	prs          []*github.PullRequest
+	prFiles      map[int][]string
+	checkRuns    map[string][]*checkRun
+	statuses     map[string][]*github.RepoStatus
+	required     map[string][]string
+	workflowRuns []*github.WorkflowRun
+	runFiles     map[int64]string
+	// pinned workflow runs keep their status when they are read
+	pinned   map[int64]bool
+	logs     map[int64]*kgithub.WorkflowRunLogs
+	jobs     map[int64][]*github.WorkflowJob
+	jobLogs  map[int64]string
+	releases []*github.RepositoryRelease
+	compare  map[string][]*github.RepositoryCommit
+}
+
+// Fake is a stateful in-memory implementation of github.API.
+// Use its setup methods to describe the state of the repositories and to simulate what the maintainers and the CI do.
+type Fake struct {
+	Login string
+	// WorkflowConclusions holds the conclusion of the runs of each workflow file, success by default
+	WorkflowConclusions map[string]string
+	// WorkflowLogs holds the logs of the runs of each workflow file, unless they are set for the run with SetWorkflowRunLogs
+	WorkflowLogs map[string]*kgithub.WorkflowRunLogs
+
+	mu     sync.Mutex
+	repos  map[repoKey]*repository
+	nextID int64
+}
+
+var _ kgithub.API = (*Fake)(nil)
+
+func NewFake() *Fake {
+	return &Fake{
+		Login:               "kuboreleaser",
+		WorkflowConclusions: make(map[string]string),
+		WorkflowLogs:        make(map[string]*kgithub.WorkflowRunLogs),
+		repos:               make(map[repoKey]*repository),
+	}
+}
+
+// repo must be called with the lock held
+func (f *Fake) repo(owner, repo string) *repository {
+	k := repoKey{owner, repo}
+	r := f.repos[k]
+	if r == nil {
+		r = &repository{
+			branches:  make(map[string]string),
+			tags:      make(map[string]*github.Tag),
+			files:     make(map[string]map[string]string),
+			comments:  make(map[int][]*github.IssueComment),
+			prFiles:   make(map[int][]string),
+			checkRuns: make(map[string][]*checkRun),
+			statuses:  make(map[string][]*github.RepoStatus),
+			required:  make(map[string][]string),
+			runFiles:  make(map[int64]string),
+			pinned:    make(map[int64]bool),
+			logs:      make(map[int64]*kgithub.WorkflowRunLogs),
+			jobs:      make(map[int64][]*github.WorkflowJob),
+			jobLogs:   make(map[int64]string),
+			compare:   make(map[string][]*github.RepositoryCommit),
+		}
+		f.repos[k] = r
+	}
+	return r
+}
+
+// id must be called with the lock held
+func (f *Fake) id() int64 {
+	f.nextID++
+	return f.nextID
+}
+
+// sha must be called with the lock held
+func (f *Fake) sha() string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprint(f.id()))))
+}
+
+// SetBranch points the branch at a new commit and returns its SHA
+func (f *Fake) SetBranch(owner, repo, name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sha := f.sha()
+	f.repo(owner, repo).branches[name] = sha
+	return sha
+}
+
+// SetFile stores the content of the file on the ref
+func (f *Fake) SetFile(owner, repo, ref, path, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	if r.files[ref] == nil {
+		r.files[ref] = make(map[string]string)
+	}
+	r.files[ref][path] = content
+}
+
+// SetCompare sets the commits which Compare returns for base...head
+func (f *Fake) SetCompare(owner, repo, base, head string, messages ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var commits []*github.RepositoryCommit
+	for _, m := range messages {
+		commits = append(commits, &github.RepositoryCommit{
+			SHA: github.String(f.sha()),
+			Commit: &github.Commit{
+				Message: github.String(m),
+			},
+		})
+	}
+	f.repo(owner, repo).compare[base+"..."+head] = commits
+}
+
+// AddCheckRun adds a check run on the ref which completes with the conclusion after it is read twice, unless SetCheckRunStatus pins it
+func (f *Fake) AddCheckRun(owner, repo, ref, name, conclusion string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id()
+	r := f.repo(owner, repo)
+	r.checkRuns[ref] = append(r.checkRuns[ref], &checkRun{
+		run: &github.CheckRun{
+			ID:      &id,
+			Name:    &name,
+			Status:  github.String("queued"),
+			HTMLURL: github.String(util.GitHubURL("%s/%s/runs/%d", owner, repo, id)),
+		},
+		conclusion: conclusion,
+	})
+}
+
+// SetCheckRunStatus moves the named check run on the ref to the status and keeps it there, a completed check run gets the conclusion it was added with
+func (f *Fake) SetCheckRunStatus(owner, repo, ref, name, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.repo(owner, repo).checkRuns[ref] {
+		if c.run.GetName() != name {
+			continue
+		}
+		c.run.Status = &status
+		c.run.Conclusion = nil
+		if status == "completed" {
+			c.run.Conclusion = github.String(c.conclusion)
+		}
+		c.pinned = true
+	}
+}
+
+// SetCommitStatus reports the state of the context on the ref through the legacy commit statuses API, replacing its previous state
+func (f *Fake) SetCommitStatus(owner, repo, ref, context, state string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	status := &github.RepoStatus{
+		ID:        github.Int64(f.id()),
+		Context:   &context,
+		State:     &state,
+		TargetURL: github.String(util.GitHubURL("%s/%s/commits/%s", owner, repo, ref)),
+	}
+	for i, s := range r.statuses[ref] {
+		if s.GetContext() == context {
+			r.statuses[ref][i] = status
+			return
+		}
+	}
+	r.statuses[ref] = append(r.statuses[ref], status)
+}
+
+// SetRequiredStatusChecks sets the contexts that the branch protection requires on the branch
+func (f *Fake) SetRequiredStatusChecks(owner, repo, branch string, contexts ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.repo(owner, repo).required[branch] = contexts
+}
+
+// MergePR merges the PR into its base branch
+func (f *Fake) MergePR(owner, repo string, number int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	for _, pr := range r.prs {
+		if pr.GetNumber() == number {
+			if pr.GetState() != "open" {
+				return fmt.Errorf("PR %d is %s", number, pr.GetState())
+			}
+			pr.State = github.String("closed")
+			pr.Merged = github.Bool(true)
+			now := time.Now()
+			pr.MergedAt = &now
+			r.branches[pr.GetBase().GetRef()] = f.sha()
+			return nil
+		}
+	}
+	return fmt.Errorf("PR %d %w", number, kgithub.ErrNotFound)
+}
+
+// SetPRFiles sets the paths of the files which the PR touches
+func (f *Fake) SetPRFiles(owner, repo string, number int, files ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.repo(owner, repo).prFiles[number] = files
+}
+
+// SetWorkflowRunLogs sets the logs which GetWorkflowRunLogs returns for the run
+func (f *Fake) SetWorkflowRunLogs(owner, repo string, id int64, logs *kgithub.WorkflowRunLogs) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.repo(owner, repo).logs[id] = logs
+}
+
+// SetWorkflowRunJobs sets the jobs which GetWorkflowRunJobs returns for the run
+func (f *Fake) SetWorkflowRunJobs(owner, repo string, id int64, jobs []*github.WorkflowJob) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.repo(owner, repo).jobs[id] = jobs
+}
+
+// SetWorkflowJobLogs sets the logs which GetWorkflowJobLogs returns for the job, jobs without logs are reported as not having them yet
+func (f *Fake) SetWorkflowJobLogs(owner, repo string, id int64, logs string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.repo(owner, repo).jobLogs[id] = logs
+}
+
+func (f *Fake) GetLogin(ctx context.Context) (string, error) {
+	return f.Login, nil
+}
+
+func (f *Fake) GetIssue(ctx context.Context, owner, repo, title string) (*github.Issue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, i := range f.repo(owner, repo).issues {
+		if i.GetTitle() == title {
+			return i, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) GetIssueByNumber(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, i := range f.repo(owner, repo).issues {
+		if i.GetNumber() == number {
+			return i, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) CreateIssue(ctx context.Context, owner, repo, title, body string) (*github.Issue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	number := len(r.issues) + len(r.prs) + 1
+	issue := &github.Issue{
+		Number:  &number,
+		Title:   &title,
+		Body:    &body,
+		State:   github.String("open"),
+		HTMLURL: github.String(util.GitHubURL("%s/%s/issues/%d", owner, repo, number)),
+	}
+	r.issues = append(r.issues, issue)
+	return issue, nil
+}
+
+func (f *Fake) GetOrCreateIssue(ctx context.Context, owner, repo, title, body string) (*github.Issue, error) {
+	issue, err := f.GetIssue(ctx, owner, repo, title)
+	if err != nil || issue != nil {
+		return issue, err
+	}
+	return f.CreateIssue(ctx, owner, repo, title, body)
+}
+
+func (f *Fake) UpdateIssue(ctx context.Context, owner, repo string, number int, body string) (*github.Issue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, i := range f.repo(owner, repo).issues {
+		if i.GetNumber() == number {
+			i.Body = &body
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("issue %d %w", number, kgithub.ErrNotFound)
+}
+
+func (f *Fake) GetIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.repo(owner, repo).comments[number] {
+		if c.GetBody() == body {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) GetIssueComments(ctx context.Context, owner, repo string, number int) ([]*github.IssueComment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*github.IssueComment(nil), f.repo(owner, repo).comments[number]...), nil
+}
+
+func (f *Fake) GetIssueCommentByID(ctx context.Context, owner, repo string, id int64) (*github.IssueComment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, comments := range f.repo(owner, repo).comments {
+		for _, c := range comments {
+			if c.GetID() == id {
+				return c, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id()
+	comment := &github.IssueComment{
+		ID:      &id,
+		Body:    &body,
+		HTMLURL: github.String(util.GitHubURL("%s/%s/issues/%d#issuecomment-%d", owner, repo, number, id)),
+	}
+	r := f.repo(owner, repo)
+	r.comments[number] = append(r.comments[number], comment)
+	return comment, nil
+}
+
+func (f *Fake) GetOrCreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	comment, err := f.GetIssueComment(ctx, owner, repo, number, body)
+	if err != nil || comment != nil {
+		return comment, err
+	}
+	return f.CreateIssueComment(ctx, owner, repo, number, body)
+}
+
+func (f *Fake) UpdateIssueComment(ctx context.Context, owner, repo string, id int64, body string) (*github.IssueComment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, comments := range f.repo(owner, repo).comments {
+		for _, c := range comments {
+			if c.GetID() == id {
+				c.Body = &body
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("comment %d %w", id, kgithub.ErrNotFound)
+}
+
+func (f *Fake) DeleteIssueComment(ctx context.Context, owner, repo string, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	for number, comments := range r.comments {
+		for i, c := range comments {
+			if c.GetID() == id {
+				r.comments[number] = append(comments[:i:i], comments[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Fake) GetBranch(ctx context.Context, owner, repo, name string) (*github.Branch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sha, ok := f.repo(owner, repo).branches[name]
+	if !ok {
+		return nil, nil
+	}
+	return &github.Branch{
+		Name: &name,
+		Commit: &github.RepositoryCommit{
+			SHA: &sha,
+		},
+	}, nil
+}
+
+func (f *Fake) CreateBranch(ctx context.Context, owner, repo, name, source string) (*github.Branch, error) {
+	f.mu.Lock()
+	r := f.repo(owner, repo)
+	sha, ok := r.branches[source]
+	if !ok {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("branch %s %w", source, kgithub.ErrNotFound)
+	}
+	r.branches[name] = sha
+	f.mu.Unlock()
+	return f.GetBranch(ctx, owner, repo, name)
+}
+
+func (f *Fake) GetOrCreateBranch(ctx context.Context, owner, repo, name, source string) (*github.Branch, error) {
+	branch, err := f.GetBranch(ctx, owner, repo, name)
+	if err != nil || branch != nil {
+		return branch, err
+	}
+	return f.CreateBranch(ctx, owner, repo, name, source)
+}
+
+func (f *Fake) DeleteBranch(ctx context.Context, owner, repo, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.repo(owner, repo).branches, name)
+	return nil
+}
+
+// GetPR returns the newest open or merged PR from the head branch, or the newest closed one if there are no others
+func (f *Fake) GetPR(ctx context.Context, owner, repo, head string) (*github.PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var found *github.PullRequest
+	prs := f.repo(owner, repo).prs
+	for i := len(prs) - 1; i >= 0; i-- {
+		if prs[i].GetHead().GetRef() != head {
+			continue
+		}
+		if prs[i].GetState() == "open" || prs[i].GetMerged() {
+			return prs[i], nil
+		}
+		if found == nil {
+			found = prs[i]
+		}
+	}
+	return found, nil
+}
+
+func (f *Fake) GetPRByNumber(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, pr := range f.repo(owner, repo).prs {
+		if pr.GetNumber() == number {
+			return pr, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) CreatePR(ctx context.Context, owner, repo, head, base, title, body string, draft bool) (*github.PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	if _, ok := r.branches[head]; !ok {
+		return nil, fmt.Errorf("branch %s %w", head, kgithub.ErrNotFound)
+	}
+	number := len(r.issues) + len(r.prs) + 1
+	pr := &github.PullRequest{
+		Number:  &number,
+		NodeID:  github.String(fmt.Sprintf("PR_%d", f.id())),
+		Title:   &title,
+		Body:    &body,
+		Draft:   &draft,
+		State:   github.String("open"),
+		Merged:  github.Bool(false),
+		User:    &github.User{Login: github.String(f.Login)},
+		HTMLURL: github.String(util.GitHubURL("%s/%s/pull/%d", owner, repo, number)),
+		Head: &github.PullRequestBranch{
+			Ref: &head,
+			SHA: github.String(r.branches[head]),
+		},
+		Base: &github.PullRequestBranch{
+			Ref: &base,
+			Repo: &github.Repository{
+				Name: &repo,
+				Owner: &github.User{
+					Login: &owner,
+				},
+			},
+		},
+	}
+	r.prs = append(r.prs, pr)
+	return pr, nil
+}
+
+func (f *Fake) GetOrCreatePR(ctx context.Context, owner, repo, head, base, title, body string, draft bool) (*github.PullRequest, error) {
+	pr, err := f.GetPR(ctx, owner, repo, head)
+	if err != nil {
+		return nil, err
+	}
+	if pr != nil && pr.GetMerged() {
+		return pr, nil
+	}
+	if pr == nil || pr.GetState() == "closed" {
+		pr, err = f.CreatePR(ctx, owner, repo, head, base, title, body, draft)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !draft && pr.GetDraft() {
+		f.mu.Lock()
+		pr.Draft = &draft
+		f.mu.Unlock()
+	}
+	return pr, nil
+}
+
+func (f *Fake) UpdatePR(ctx context.Context, pr *github.PullRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.repo(pr.GetBase().GetRepo().GetOwner().GetLogin(), pr.GetBase().GetRepo().GetName()).prs {
+		if p.GetNumber() == pr.GetNumber() {
+			p.Title = pr.Title
+			p.Body = pr.Body
+			return nil
+		}
+	}
+	return fmt.Errorf("PR %d %w", pr.GetNumber(), kgithub.ErrNotFound)
+}
+
+func (f *Fake) EnableAutoMerge(ctx context.Context, pr *github.PullRequest, method kgithub.MergeMethod) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.repo(pr.GetBase().GetRepo().GetOwner().GetLogin(), pr.GetBase().GetRepo().GetName()).prs {
+		if p.GetNumber() == pr.GetNumber() {
+			if p.GetState() != "open" || p.GetDraft() {
+				return fmt.Errorf("auto-merge cannot be enabled on PR %d", pr.GetNumber())
+			}
+			p.AutoMerge = &github.PullRequestAutoMerge{
+				MergeMethod: github.String(strings.ToLower(string(method))),
+			}
+			pr.AutoMerge = p.AutoMerge
+			return nil
+		}
+	}
+	return fmt.Errorf("PR %d %w", pr.GetNumber(), kgithub.ErrNotFound)
+}
+
+// pr must be called with the lock held
+func (f *Fake) pr(pr *github.PullRequest) (*github.PullRequest, error) {
+	for _, p := range f.repo(pr.GetBase().GetRepo().GetOwner().GetLogin(), pr.GetBase().GetRepo().GetName()).prs {
+		if p.GetNumber() == pr.GetNumber() {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("PR %d %w", pr.GetNumber(), kgithub.ErrNotFound)
+}
+
+func (f *Fake) GetPRFiles(ctx context.Context, pr *github.PullRequest) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.repo(pr.GetBase().GetRepo().GetOwner().GetLogin(), pr.GetBase().GetRepo().GetName()).prFiles[pr.GetNumber()], nil
+}
+
+func (f *Fake) RequestReviewers(ctx context.Context, pr *github.PullRequest, reviewers, teams []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, err := f.pr(pr)
+	if err != nil {
+		return err
+	}
+	requested := make(map[string]bool)
+	for _, u := range p.RequestedReviewers {
+		requested[u.GetLogin()] = true
+	}
+	for _, t := range p.RequestedTeams {
+		requested["@"+t.GetSlug()] = true
+	}
+	for _, r := range reviewers {
+		if !requested[r] {
+			p.RequestedReviewers = append(p.RequestedReviewers, &github.User{Login: github.String(r)})
+		}
+	}
+	for _, t := range teams {
+		if !requested["@"+t] {
+			p.RequestedTeams = append(p.RequestedTeams, &github.Team{Slug: github.String(t)})
+		}
+	}
+	pr.RequestedReviewers = p.RequestedReviewers
+	pr.RequestedTeams = p.RequestedTeams
+	return nil
+}
+
+func (f *Fake) AddAssignees(ctx context.Context, pr *github.PullRequest, assignees []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, err := f.pr(pr)
+	if err != nil {
+		return err
+	}
+	assigned := make(map[string]bool)
+	for _, u := range p.Assignees {
+		assigned[u.GetLogin()] = true
+	}
+	for _, a := range assignees {
+		if !assigned[a] {
+			p.Assignees = append(p.Assignees, &github.User{Login: github.String(a)})
+		}
+	}
+	pr.Assignees = p.Assignees
+	return nil
+}
+
+func (f *Fake) ClosePR(ctx context.Context, pr *github.PullRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.repo(pr.GetBase().GetRepo().GetOwner().GetLogin(), pr.GetBase().GetRepo().GetName()).prs {
+		if p.GetNumber() == pr.GetNumber() {
+			p.State = github.String("closed")
+			return nil
+		}
+	}
+	return fmt.Errorf("PR %d %w", pr.GetNumber(), kgithub.ErrNotFound)
+}
+
+func (f *Fake) GetFile(ctx context.Context, owner, repo, path, ref string) (*github.RepositoryContent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	content, ok := f.repo(owner, repo).files[ref][path]
+	if !ok {
+		return nil, nil
+	}
+	return &github.RepositoryContent{
+		Path:     &path,
+		Encoding: github.String("base64"),
+		Content:  github.String(base64.StdEncoding.EncodeToString([]byte(content))),
+		HTMLURL:  github.String(util.GitHubURL("%s/%s/blob/%s/%s", owner, repo, ref, path)),
+	}, nil
+}
+
+func (f *Fake) Compare(ctx context.Context, owner, repo, base, head string) ([]*github.RepositoryCommit, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.repo(owner, repo).compare[base+"..."+head], nil
+}
+
+// GetCheckRuns advances every check run on the ref by one step
+func (f *Fake) GetCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var runs []*github.CheckRun
+	for _, c := range f.repo(owner, repo).checkRuns[ref] {
+		run := *c.run
+		runs = append(runs, &run)
+		if c.pinned {
+			continue
+		}
+		switch c.run.GetStatus() {
+		case "queued":
+			c.run.Status = github.String("in_progress")
+		case "in_progress":
+			c.run.Status = github.String("completed")
+			c.run.Conclusion = github.String(c.conclusion)
+		}
+	}
+	return runs, nil
+}
+
+func (f *Fake) GetIncompleteCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error) {
+	runs, err := f.GetCheckRuns(ctx, owner, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	var incomplete []*github.CheckRun
+	for _, r := range runs {
+		if r.GetStatus() != "completed" {
+			incomplete = append(incomplete, r)
+		}
+	}
+	return incomplete, nil
+}
+
+func (f *Fake) GetUnsuccessfulCheckRuns(ctx context.Context, owner, repo, ref string) ([]*github.CheckRun, error) {
+	runs, err := f.GetCheckRuns(ctx, owner, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	var unsuccessful []*github.CheckRun
+	for _, r := range runs {
+		if r.GetStatus() == "completed" && r.GetConclusion() != "success" && r.GetConclusion() != "skipped" {
+			unsuccessful = append(unsuccessful, r)
+		}
+	}
+	return unsuccessful, nil
+}
+
+func (f *Fake) GetRequiredStatusChecks(ctx context.Context, owner, repo, branch string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.repo(owner, repo).required[branch], nil
+}
+
+func (f *Fake) GetCommitStatuses(ctx context.Context, owner, repo, ref string) ([]*github.RepoStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var statuses []*github.RepoStatus
+	for _, s := range f.repo(owner, repo).statuses[ref] {
+		status := *s
+		statuses = append(statuses, &status)
+	}
+	return statuses, nil
+}
+
+func (f *Fake) CreateWorkflowRun(ctx context.Context, owner, repo, file, ref string, inputs ...kgithub.WorkflowRunInput) (*kgithub.WorkflowRunHandle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id()
+	r := f.repo(owner, repo)
+	r.runFiles[id] = file
+	now := time.Now()
+	run := &github.WorkflowRun{
+		ID:         &id,
+		HeadBranch: &ref,
+		Event:      github.String("workflow_dispatch"),
+		Status:     github.String("queued"),
+		RunAttempt: github.Int(1),
+		CreatedAt:  &github.Timestamp{Time: now},
+		HTMLURL:    github.String(util.GitHubURL("%s/%s/actions/runs/%d", owner, repo, id)),
+		Actor:      &github.User{Login: github.String(f.Login)},
+	}
+	r.workflowRuns = append(r.workflowRuns, run)
+	return &kgithub.WorkflowRunHandle{
+		Owner:        owner,
+		Repo:         repo,
+		File:         file,
+		Ref:          ref,
+		Actor:        f.Login,
+		DispatchedAt: now,
+		ID:           id,
+		URL:          run.GetHTMLURL(),
+	}, nil
+}
+
+// FindWorkflowRun returns the oldest run of the workflow the actor dispatched on the ref since the handle was created
+func (f *Fake) FindWorkflowRun(ctx context.Context, handle *kgithub.WorkflowRunHandle) (*github.WorkflowRun, error) {
+	if handle.ID != 0 {
+		return f.GetWorkflowRunByID(ctx, handle.Owner, handle.Repo, handle.ID)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(handle.Owner, handle.Repo)
+	for _, run := range r.workflowRuns {
+		if run.GetHeadBranch() != handle.Ref || r.runFiles[run.GetID()] != handle.File || run.GetActor().GetLogin() != handle.Actor || run.GetCreatedAt().Before(handle.DispatchedAt) {
+			continue
+		}
+		handle.ID = run.GetID()
+		handle.URL = run.GetHTMLURL()
+		copy := *run
+		return &copy, nil
+	}
+	return nil, nil
+}
+
+// GetWorkflowRun returns the newest run of the workflow on the branch and advances all its runs by one step
+func (f *Fake) GetWorkflowRun(ctx context.Context, owner, repo, branch, file string, completed bool) (*github.WorkflowRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var found *github.WorkflowRun
+	r := f.repo(owner, repo)
+	for i := len(r.workflowRuns) - 1; i >= 0; i-- {
+		run := r.workflowRuns[i]
+		if run.GetHeadBranch() != branch || r.runFiles[run.GetID()] != file {
+			continue
+		}
+		if found == nil && (!completed || run.GetStatus() == "completed") {
+			copy := *run
+			found = &copy
+		}
+		f.advance(r, run)
+	}
+	return found, nil
+}
+
+// advance moves the run one step closer to completion unless it is pinned, it must be called with the lock held
+func (f *Fake) advance(r *repository, run *github.WorkflowRun) {
+	if r.pinned[run.GetID()] {
+		return
+	}
+	switch run.GetStatus() {
+	case "queued":
+		run.Status = github.String("in_progress")
+	case "in_progress":
+		conclusion, ok := f.WorkflowConclusions[r.runFiles[run.GetID()]]
+		if !ok {
+			conclusion = "success"
+		}
+		run.Status = github.String("completed")
+		run.Conclusion = &conclusion
+	}
+}
+
+// SetWorkflowRunStatus moves the run to the status and keeps it there until it is rerun, a completed run gets the conclusion of its workflow
+func (f *Fake) SetWorkflowRunStatus(owner, repo string, id int64, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	for _, run := range r.workflowRuns {
+		if run.GetID() != id {
+			continue
+		}
+		run.Status = &status
+		run.Conclusion = nil
+		if status == "completed" {
+			conclusion, ok := f.WorkflowConclusions[r.runFiles[id]]
+			if !ok {
+				conclusion = "success"
+			}
+			run.Conclusion = &conclusion
+		}
+		r.pinned[id] = true
+	}
+}
+
+// GetWorkflowRunByID returns the run and advances it by one step
+func (f *Fake) GetWorkflowRunByID(ctx context.Context, owner, repo string, id int64) (*github.WorkflowRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	for _, run := range r.workflowRuns {
+		if run.GetID() == id {
+			copy := *run
+			f.advance(r, run)
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) CountWorkflowRuns(ctx context.Context, owner, repo, ref string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, run := range f.repo(owner, repo).workflowRuns {
+		if run.GetHeadBranch() == ref {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// RerunFailedJobs starts a new attempt of the run, which concludes like the first one unless WorkflowConclusions changed in the meantime
+func (f *Fake) RerunFailedJobs(ctx context.Context, owner, repo string, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	for _, run := range r.workflowRuns {
+		if run.GetID() == id {
+			delete(r.pinned, id)
+			run.Status = github.String("queued")
+			run.Conclusion = nil
+			run.RunAttempt = github.Int(run.GetRunAttempt() + 1)
+			return nil
+		}
+	}
+	return fmt.Errorf("run %d %w", id, kgithub.ErrNotFound)
+}
+
+// GetWorkflowRunLogs returns the logs set for the run or for its workflow, whatever the attempt
+func (f *Fake) GetWorkflowRunLogs(ctx context.Context, owner, repo string, id int64, attempt int) (*kgithub.WorkflowRunLogs, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	if logs, ok := r.logs[id]; ok {
+		return logs, nil
+	}
+	if logs, ok := f.WorkflowLogs[r.runFiles[id]]; ok {
+		return logs, nil
+	}
+	return kgithub.NewWorkflowRunLogs(nil), nil
+}
+
+func (f *Fake) GetWorkflowRunJobs(ctx context.Context, owner, repo string, id int64) ([]*github.WorkflowJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.repo(owner, repo).jobs[id], nil
+}
+
+func (f *Fake) GetWorkflowJobLogs(ctx context.Context, owner, repo string, id int64) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	logs, ok := f.repo(owner, repo).jobLogs[id]
+	if !ok {
+		return nil, nil
+	}
+	return io.NopCloser(strings.NewReader(logs)), nil
+}
+
+func (f *Fake) GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	releases := f.repo(owner, repo).releases
+	latest := make([]*github.RepositoryRelease, 0, len(releases))
+	for _, r := range releases {
+		if r.GetMakeLatest() == "true" {
+			latest = append(latest, r)
+		}
+	}
+	if len(latest) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(latest, func(i, j int) bool {
+		return latest[i].GetID() < latest[j].GetID()
+	})
+	return latest[len(latest)-1], nil
+}
+
+func (f *Fake) GetRelease(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.repo(owner, repo).releases {
+		if r.GetTagName() == tag {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) CreateRelease(ctx context.Context, owner, repo, tag, name, body string, prerelease bool, latest bool) (*github.RepositoryRelease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.id()
+	makeLatest := "false"
+	if latest {
+		makeLatest = "true"
+	}
+	release := &github.RepositoryRelease{
+		ID:         &id,
+		TagName:    &tag,
+		Name:       &name,
+		Body:       &body,
+		Prerelease: &prerelease,
+		MakeLatest: &makeLatest,
+		HTMLURL:    github.String(util.GitHubURL("%s/%s/releases/tag/%s", owner, repo, tag)),
+	}
+	r := f.repo(owner, repo)
+	r.releases = append(r.releases, release)
+	return release, nil
+}
+
+func (f *Fake) GetOrCreateRelease(ctx context.Context, owner, repo, tag, name, body string, prerelease bool, latest bool) (*github.RepositoryRelease, error) {
+	release, err := f.GetRelease(ctx, owner, repo, tag)
+	if err != nil || release != nil {
+		return release, err
+	}
+	return f.CreateRelease(ctx, owner, repo, tag, name, body, prerelease, latest)
+}
+
+func (f *Fake) DeleteRelease(ctx context.Context, owner, repo string, release *github.RepositoryRelease) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.repo(owner, repo)
+	for i, rel := range r.releases {
+		if rel.GetID() == release.GetID() {
+			r.releases = append(r.releases[:i:i], r.releases[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// SetTag creates an annotated tag of a new commit
+func (f *Fake) SetTag(owner, repo, tag, message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.repo(owner, repo).tags[tag] = &github.Tag{
+		Tag:     &tag,
+		SHA:     github.String(f.sha()),
+		Message: &message,
+		Object: &github.GitObject{
+			Type: github.String("commit"),
+			SHA:  github.String(f.sha()),
+		},
+	}
+}
+
+func (f *Fake) GetTag(ctx context.Context, owner, repo, tag string) (*github.Tag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.repo(owner, repo).tags[tag], nil
+}
+
+func (f *Fake) DeleteTag(ctx context.Context, owner, repo, tag string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.repo(owner, repo).tags, tag)
+	return nil
+}
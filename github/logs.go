@@ -0,0 +1,387 @@
+package github
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/ipfs/kuboreleaser/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// logsCacheMaxAge is how long a log archive is kept after it was last used, a release does not take longer
+	logsCacheMaxAge = 30 * 24 * time.Hour
+	// logsCacheMaxSize caps the size of all the log archives together, the least recently used ones are removed first
+	logsCacheMaxSize = 1 << 30
+)
+
+// logFile is a single file of the log archive, *zip.File is one
+type logFile interface {
+	Open() (io.ReadCloser, error)
+}
+
+type memLogFile string
+
+func (f memLogFile) Open() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(string(f))), nil
+}
+
+type WorkflowRunJobStepLogs struct {
+	Name  string
+	files []logFile
+}
+
+// Open returns a reader of the logs of the step, the files of the archive are only read as the reader advances
+func (s *WorkflowRunJobStepLogs) Open() io.ReadCloser {
+	return &logReader{files: s.files}
+}
+
+type WorkflowRunJobLogs struct {
+	Name        string
+	JobStepLogs map[string]*WorkflowRunJobStepLogs
+	files       []logFile
+}
+
+// Open returns a reader of the logs of the whole job
+func (j *WorkflowRunJobLogs) Open() io.ReadCloser {
+	return &logReader{files: j.files}
+}
+
+// WorkflowRunLogs indexes the jobs and steps of a log archive, it has to be closed once it is not needed anymore
+type WorkflowRunLogs struct {
+	JobLogs map[string]*WorkflowRunJobLogs
+	closer  io.Closer
+}
+
+// NewWorkflowRunLogs returns logs kept in memory, keyed by their name in the archive, e.g. 0_build.txt for a job or build/1_Set up job.txt for a step
+func NewWorkflowRunLogs(files map[string]string) *WorkflowRunLogs {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	logs := &WorkflowRunLogs{
+		JobLogs: make(map[string]*WorkflowRunJobLogs),
+	}
+	for _, name := range names {
+		logs.add(name, memLogFile(files[name]))
+	}
+	return logs
+}
+
+func (l *WorkflowRunLogs) add(name string, f logFile) {
+	name = strings.TrimSuffix(name, ".txt")
+	if strings.HasSuffix(name, ")") {
+		name = name[:strings.LastIndex(name, "(")-1]
+	}
+	job, step, _ := strings.Cut(name, "/")
+	if step == "" {
+		_, job, _ = strings.Cut(job, "_")
+	} else {
+		_, step, _ = strings.Cut(step, "_")
+	}
+	j := l.JobLogs[job]
+	if j == nil {
+		j = &WorkflowRunJobLogs{
+			Name:        job,
+			JobStepLogs: make(map[string]*WorkflowRunJobStepLogs),
+		}
+		l.JobLogs[job] = j
+	}
+	if step == "" {
+		j.files = append(j.files, f)
+	} else {
+		s := j.JobStepLogs[step]
+		if s == nil {
+			s = &WorkflowRunJobStepLogs{
+				Name: step,
+			}
+			j.JobStepLogs[step] = s
+		}
+		s.files = append(s.files, f)
+	}
+}
+
+func (l *WorkflowRunLogs) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// logReader reads the files one after the other, opening each only once the previous one is exhausted
+type logReader struct {
+	files   []logFile
+	current io.ReadCloser
+}
+
+func (r *logReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.files) == 0 {
+				return 0, io.EOF
+			}
+			current, err := r.files[0].Open()
+			if err != nil {
+				return 0, err
+			}
+			r.current = current
+			r.files = r.files[1:]
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *logReader) Close() error {
+	r.files = nil
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// GetWorkflowRunLogs returns the logs of an attempt of a completed run.
+// The log archive is streamed to the cache directory on the first call, the logs of a completed attempt never change.
+func (c *Client) GetWorkflowRunLogs(ctx context.Context, owner, repo string, id int64, attempt int) (*WorkflowRunLogs, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner":   owner,
+		"repo":    repo,
+		"id":      id,
+		"attempt": attempt,
+	}).Debug("Searching for workflow run logs...")
+
+	dir := filepath.Join(util.CacheDir(), "logs")
+	path := filepath.Join(dir, owner, repo, fmt.Sprintf("%d-%d.zip", id, attempt))
+	if _, err := os.Stat(path); err != nil {
+		err = c.downloadWorkflowRunLogs(ctx, owner, repo, id, attempt, path)
+		if err != nil {
+			return nil, err
+		}
+		evictWorkflowRunLogs(ctx, dir, path)
+	} else {
+		util.Logger(ctx).WithField("path", path).Debug("Using cached workflow run logs")
+		// the modification time tells when the archive was last used
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+	}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("🚨 the cached workflow run logs %s are corrupted, remove them and try again: %w", path, err)
+	}
+
+	logs := &WorkflowRunLogs{
+		JobLogs: make(map[string]*WorkflowRunJobLogs),
+		closer:  reader,
+	}
+	files := append([]*zip.File(nil), reader.File...)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	for _, f := range files {
+		if !f.FileInfo().IsDir() {
+			logs.add(f.Name, f)
+		}
+	}
+
+	util.Logger(ctx).WithFields(log.Fields{
+		"path": path,
+	}).Debug("Got workflow run logs")
+
+	return logs, nil
+}
+
+// evictWorkflowRunLogs removes the log archives which were not used for logsCacheMaxAge, then the least recently used ones until they fit in logsCacheMaxSize.
+// The archive at keep was just downloaded and is never removed.
+func evictWorkflowRunLogs(ctx context.Context, dir, keep string) {
+	type archive struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var archives []archive
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".zip" || path == keep {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		archives = append(archives, archive{path: path, size: info.Size(), used: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		util.Logger(ctx).Warn("⚠️ Failed to evict the cached workflow run logs: ", err)
+		return
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].used.After(archives[j].used)
+	})
+	var total int64
+	if info, err := os.Stat(keep); err == nil {
+		total = info.Size()
+	}
+	for _, a := range archives {
+		total += a.size
+		if total <= logsCacheMaxSize && time.Since(a.used) <= logsCacheMaxAge {
+			continue
+		}
+		util.Logger(ctx).WithField("path", a.path).Debug("Evicting cached workflow run logs")
+		err := os.Remove(a.path)
+		if err != nil {
+			util.Logger(ctx).Warn("⚠️ Failed to evict the cached workflow run logs: ", err)
+		}
+		total -= a.size
+	}
+}
+
+func (c *Client) downloadWorkflowRunLogs(ctx context.Context, owner, repo string, id int64, attempt int, path string) error {
+	// go-github only knows about the logs of the latest attempt, so we ask for the redirect to the archive ourselves
+	req, err := c.v3.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/actions/runs/%d/attempts/%d/logs", owner, repo, id, attempt), nil)
+	if err != nil {
+		return err
+	}
+	// the redirect is not followed with the client's transport, which would hand the token to the storage of the archive
+	resp, err := c.limits.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		err = wrapError(github.CheckResponse(resp))
+		if err == nil {
+			err = fmt.Errorf("🚨 unexpected response to the request for the logs of %s/%s/%s/actions/runs/%d/attempts/%d: %s", util.GitHubServerURL(), owner, repo, id, attempt, resp.Status)
+		}
+		return err
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, resp.Header.Get("Location"), nil)
+	if err != nil {
+		return err
+	}
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("🚨 failed to download the logs of %s/%s/%s/actions/runs/%d/attempts/%d: %s", util.GitHubServerURL(), owner, repo, id, attempt, r.Status)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	// the archive is written to a temporary file first so that an interrupted download is never mistaken for the logs
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, r.Body)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	util.Logger(ctx).WithFields(log.Fields{
+		"path": path,
+		"size": n,
+	}).Debug("Downloaded workflow run logs")
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// GetWorkflowRunJobs returns the jobs of the latest attempt of the run, with their steps
+func (c *Client) GetWorkflowRunJobs(ctx context.Context, owner, repo string, id int64) ([]*github.WorkflowJob, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"id":    id,
+	}).Debug("Searching for workflow run jobs...")
+
+	opt := &github.ListWorkflowJobsOptions{
+		Filter:      "latest",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var jobs []*github.WorkflowJob
+	for {
+		j, r, err := c.v3.Actions.ListWorkflowJobs(ctx, owner, repo, id, opt)
+		err = wrapError(err)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j.Jobs...)
+		if r.NextPage == 0 {
+			break
+		}
+		opt.Page = r.NextPage
+	}
+
+	util.Logger(ctx).WithFields(log.Fields{
+		"jobs": len(jobs),
+	}).Debug("Found workflow run jobs")
+
+	return jobs, nil
+}
+
+// GetWorkflowJobLogs streams the logs of the job, it returns nil if they are not available yet
+func (c *Client) GetWorkflowJobLogs(ctx context.Context, owner, repo string, id int64) (io.ReadCloser, error) {
+	util.Logger(ctx).WithFields(log.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"id":    id,
+	}).Debug("Searching for workflow job logs...")
+
+	url, _, err := c.v3.Actions.GetWorkflowJobLogs(ctx, owner, repo, id, true)
+	err = wrapError(err)
+	if errors.Is(err, ErrNotFound) {
+		util.Logger(ctx).Debug("Workflow job logs not available yet")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		r.Body.Close()
+		return nil, fmt.Errorf("🚨 failed to download the logs of the job %d of %s/%s/%s: %s", id, util.GitHubServerURL(), owner, repo, r.Status)
+	}
+	return r.Body, nil
+}
@@ -0,0 +1,246 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/kuboreleaser/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxRetries = 5
+	// GitHub asks to wait at least a minute after hitting a secondary rate limit without a Retry-After
+	secondaryRateLimitDelay = time.Minute
+	maxBackoff              = 30 * time.Second
+)
+
+// RateLimit is the last known quota of one of the GitHub API resources, e.g. core, search or graphql
+type RateLimit struct {
+	Resource  string    `json:"resource"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// rateLimitTransport keeps track of the X-RateLimit-* headers and retries the requests which were rate limited or failed transiently.
+// It waits for the quota to reset instead of sending requests which are bound to be rejected.
+type rateLimitTransport struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	limits map[string]RateLimit
+}
+
+func newRateLimitTransport(base http.RoundTripper) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:   base,
+		limits: make(map[string]RateLimit),
+	}
+}
+
+// resource guesses which quota the request counts against, the response tells for sure
+func resource(req *http.Request) string {
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/search/") || strings.Contains(req.URL.Path, "/api/v3/search/"):
+		return "search"
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return "graphql"
+	default:
+		return "core"
+	}
+}
+
+// idempotent reports whether the request can be sent again after a network error or a 5xx.
+// The GraphQL queries and the mutations we send are safe to repeat.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return strings.HasSuffix(req.URL.Path, "/graphql")
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func backoff(attempt int) time.Duration {
+	d := time.Second << attempt
+	if d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	logger := util.Logger(ctx).WithFields(log.Fields{
+		"method": req.Method,
+		"url":    req.URL.String(),
+	})
+
+	for attempt := 0; ; attempt++ {
+		err := t.waitForQuota(ctx, resource(req))
+		if err != nil {
+			return nil, err
+		}
+
+		r := req
+		if attempt > 0 {
+			r = req.Clone(ctx)
+			if req.Body != nil {
+				r.Body, err = req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if resp != nil {
+			t.update(resp)
+		}
+
+		delay, reason := t.retry(req, resp, err, attempt)
+		if reason == "" {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		logger.WithField("attempt", attempt+1).Warn("⚠️ ", reason, ", retrying in ", delay.Round(time.Second), "...")
+		err = sleep(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retry returns how long to wait before sending the request again and why, or an empty reason if it should not be sent again
+func (t *rateLimitTransport) retry(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, string) {
+	if attempt >= maxRetries || (req.Body != nil && req.GetBody == nil) {
+		return 0, ""
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || !idempotent(req) {
+			return 0, ""
+		}
+		return backoff(attempt), fmt.Sprintf("request failed: %v", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if s := resp.Header.Get("Retry-After"); s != "" {
+			if seconds, err := strconv.Atoi(s); err == nil {
+				return time.Duration(seconds) * time.Second, "GitHub asked to retry later"
+			}
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				return time.Until(time.Unix(reset, 0)) + time.Second, "the GitHub rate limit was exceeded"
+			}
+		}
+		// the body has to be put back in case the response is returned after all
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if bytes.Contains(bytes.ToLower(body), []byte("secondary rate limit")) {
+			return secondaryRateLimitDelay << attempt, "the GitHub secondary rate limit was exceeded"
+		}
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if idempotent(req) {
+			return backoff(attempt), fmt.Sprintf("GitHub responded with %s", resp.Status)
+		}
+	}
+	return 0, ""
+}
+
+func (t *rateLimitTransport) update(resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	rate := RateLimit{
+		Resource:  resp.Header.Get("X-RateLimit-Resource"),
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+	if rate.Resource == "" {
+		rate.Resource = resource(resp.Request)
+	}
+
+	t.mu.Lock()
+	t.limits[rate.Resource] = rate
+	t.mu.Unlock()
+
+	util.Logger(resp.Request.Context()).WithFields(log.Fields{
+		"resource":  rate.Resource,
+		"remaining": rate.Remaining,
+		"limit":     rate.Limit,
+		"reset":     rate.Reset.Format(time.TimeOnly),
+	}).Debug("GitHub API quota")
+
+	// go-github refuses to send requests while it knows the quota is exhausted, we wait for the reset in waitForQuota instead
+	if remaining == 0 && resp.StatusCode < 300 {
+		resp.Header.Del("X-RateLimit-Reset")
+	}
+}
+
+func (t *rateLimitTransport) waitForQuota(ctx context.Context, resource string) error {
+	t.mu.Lock()
+	rate, ok := t.limits[resource]
+	t.mu.Unlock()
+	if !ok || rate.Remaining > 0 || time.Now().After(rate.Reset) {
+		return nil
+	}
+
+	util.Logger(ctx).WithField("resource", resource).Warn("⚠️ The GitHub rate limit is exhausted, waiting until it resets at ", rate.Reset.Format(time.TimeOnly), "...")
+	err := sleep(ctx, time.Until(rate.Reset)+time.Second)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	if t.limits[resource] == rate {
+		delete(t.limits, resource)
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// RateLimits returns the last known quotas, ordered by resource
+func (t *rateLimitTransport) RateLimits() []RateLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limits := make([]RateLimit, 0, len(t.limits))
+	for _, rate := range t.limits {
+		limits = append(limits, rate)
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		return limits[i].Resource < limits[j].Resource
+	})
+	return limits
+}
@@ -0,0 +1,309 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Status string
+
+const (
+	StatusDone       Status = "done"
+	StatusInProgress Status = "in progress"
+	StatusIncomplete Status = "incomplete"
+	StatusFailed     Status = "failed"
+)
+
+type ArtifactKind string
+
+const (
+	ArtifactBranch       ArtifactKind = "branch"
+	ArtifactCommit       ArtifactKind = "commit"
+	ArtifactTag          ArtifactKind = "tag"
+	ArtifactPR           ArtifactKind = "pr"
+	ArtifactIssue        ArtifactKind = "issue"
+	ArtifactIssueComment ArtifactKind = "issue-comment"
+	ArtifactRelease      ArtifactKind = "release"
+	ArtifactWorkflowRun  ArtifactKind = "workflow-run"
+)
+
+// Artifact is a piece of remote state created by an action.
+type Artifact struct {
+	Kind  ArtifactKind `json:"kind"`
+	Owner string       `json:"owner"`
+	Repo  string       `json:"repo"`
+	Name  string       `json:"name,omitempty"`
+	ID    int64        `json:"id,omitempty"`
+	// SHA is the commit a branch pointed to when it was created
+	SHA string `json:"sha,omitempty"`
+	// Ref, Actor and CorrelationID identify the run a workflow dispatch triggered, they are kept once its ID is known
+	Ref           string    `json:"ref,omitempty"`
+	Actor         string    `json:"actor,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	URL           string    `json:"url,omitempty"`
+	Time          time.Time `json:"time"`
+	// RevertedAt is set once the artifact was reverted, so that it is not reverted again
+	RevertedAt *time.Time `json:"reverted_at,omitempty"`
+}
+
+func (a Artifact) same(other Artifact) bool {
+	if a.Kind != other.Kind || a.Owner != other.Owner || a.Repo != other.Repo || a.Name != other.Name {
+		return false
+	}
+	// a workflow run gets its ID only after it is found, the dispatch time tells the runs apart until then
+	if a.Kind == ArtifactWorkflowRun {
+		return a.Time.Equal(other.Time)
+	}
+	return a.ID == other.ID
+}
+
+type Recorder interface {
+	Record(artifact Artifact)
+}
+
+type Check struct {
+	Time   time.Time `json:"time"`
+	Status Status    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Entry holds everything we know about a single action of the release.
+// All the methods are safe to call on a nil entry, in which case they do nothing.
+type Entry struct {
+	Name       string     `json:"name"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Check      *Check     `json:"check,omitempty"`
+	RevertedAt *time.Time `json:"reverted_at,omitempty"`
+	Artifacts  []Artifact `json:"artifacts,omitempty"`
+
+	journal *Journal
+}
+
+type Journal struct {
+	Version string   `json:"version"`
+	Entries []*Entry `json:"entries"`
+
+	path string
+	mu   sync.Mutex
+}
+
+func Open(dir, version string) (*Journal, error) {
+	path := filepath.Join(dir, fmt.Sprintf("%s.json", version))
+
+	log.WithFields(log.Fields{
+		"path": path,
+	}).Debug("Opening journal...")
+
+	j := &Journal{
+		Version: version,
+		path:    path,
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Debug("Journal not found, starting a new one")
+			return j, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, j)
+	if err != nil {
+		return nil, fmt.Errorf("🚨 journal %s is corrupted: %w", path, err)
+	}
+	if j.Version != version {
+		return nil, fmt.Errorf("🚨 journal %s belongs to %s, not %s", path, j.Version, version)
+	}
+	for _, e := range j.Entries {
+		e.journal = j
+	}
+
+	log.WithFields(log.Fields{
+		"entries": len(j.Entries),
+	}).Debug("Opened journal")
+
+	return j, nil
+}
+
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Lookup returns the entry for the named action, or nil if there is none.
+func (j *Journal) Lookup(name string) *Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, e := range j.Entries {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Entry returns the entry for the named action, creating it if needed.
+func (j *Journal) Entry(name string) *Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, e := range j.Entries {
+		if e.Name == name {
+			return e
+		}
+	}
+	e := &Entry{
+		Name:    name,
+		journal: j,
+	}
+	j.Entries = append(j.Entries, e)
+	return e
+}
+
+// save must be called with the lock held.
+func (j *Journal) save() {
+	err := os.MkdirAll(filepath.Dir(j.path), 0755)
+	if err == nil {
+		var b []byte
+		b, err = json.MarshalIndent(j, "", "  ")
+		if err == nil {
+			// write to a temporary file first so that a crash never leaves a truncated journal behind
+			tmp := j.path + ".tmp"
+			err = os.WriteFile(tmp, b, 0644)
+			if err == nil {
+				err = os.Rename(tmp, j.path)
+			}
+		}
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"path": j.path,
+		}).Warn("Failed to save journal: ", err)
+	}
+}
+
+func (e *Entry) update(fn func()) {
+	if e == nil {
+		return
+	}
+	e.journal.mu.Lock()
+	defer e.journal.mu.Unlock()
+	fn()
+	e.journal.save()
+}
+
+func (e *Entry) Start() {
+	e.update(func() {
+		now := time.Now()
+		e.StartedAt = &now
+		e.FinishedAt = nil
+	})
+}
+
+func (e *Entry) Finish() {
+	e.update(func() {
+		now := time.Now()
+		e.FinishedAt = &now
+	})
+}
+
+func (e *Entry) Checked(status Status, err error) {
+	e.update(func() {
+		e.Check = &Check{
+			Time:   time.Now(),
+			Status: status,
+		}
+		if err != nil {
+			e.Check.Error = err.Error()
+		}
+	})
+}
+
+func (e *Entry) Record(artifact Artifact) {
+	e.update(func() {
+		if artifact.Time.IsZero() {
+			artifact.Time = time.Now()
+		}
+		for i, a := range e.Artifacts {
+			if a.same(artifact) {
+				e.Artifacts[i] = artifact
+				return
+			}
+		}
+		e.Artifacts = append(e.Artifacts, artifact)
+	})
+}
+
+// Reverted marks the action as not started and its artifacts as reverted, they are kept so that we still know what was created
+func (e *Entry) Reverted() {
+	e.update(func() {
+		now := time.Now()
+		e.RevertedAt = &now
+		e.StartedAt = nil
+		e.FinishedAt = nil
+		e.Check = nil
+		for i := range e.Artifacts {
+			if e.Artifacts[i].RevertedAt == nil {
+				e.Artifacts[i].RevertedAt = &now
+			}
+		}
+	})
+}
+
+// ArtifactsReverted marks the artifacts as reverted when only some of them could be, the action is left as it is
+func (e *Entry) ArtifactsReverted(artifacts []Artifact) {
+	e.update(func() {
+		now := time.Now()
+		for i := range e.Artifacts {
+			for _, a := range artifacts {
+				if e.Artifacts[i].same(a) && e.Artifacts[i].RevertedAt == nil {
+					e.Artifacts[i].RevertedAt = &now
+				}
+			}
+		}
+	})
+}
+
+// GetArtifacts returns a copy of the artifacts recorded for the entry
+func (e *Entry) GetArtifacts() []Artifact {
+	if e == nil {
+		return nil
+	}
+	e.journal.mu.Lock()
+	defer e.journal.mu.Unlock()
+	return append([]Artifact(nil), e.Artifacts...)
+}
+
+// URLs returns the URLs of the artifacts recorded for the entry
+func (e *Entry) URLs() []string {
+	if e == nil {
+		return nil
+	}
+	e.journal.mu.Lock()
+	defer e.journal.mu.Unlock()
+	var urls []string
+	for _, a := range e.Artifacts {
+		if a.URL != "" {
+			urls = append(urls, a.URL)
+		}
+	}
+	return urls
+}
+
+func (e *Entry) IsFinished() bool {
+	if e == nil {
+		return false
+	}
+	e.journal.mu.Lock()
+	defer e.journal.mu.Unlock()
+	return e.FinishedAt != nil
+}
@@ -1,7 +1,11 @@
 package matrix
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 
 	"github.com/ipfs/kuboreleaser/util"
@@ -44,31 +48,69 @@ func NewClient() (*Client, error) {
 	}, nil
 }
 
+// get mirrors gomatrix.Client.MakeRequest for GET requests but lets the caller cancel the request
+func (c *Client) get(ctx context.Context, url string, resBody interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.matrix.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.matrix.AccessToken)
+	}
+
+	res, err := c.matrix.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	contents, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode/100 != 2 {
+		var wrap error
+		var respErr gomatrix.RespError
+		if _ = json.Unmarshal(contents, &respErr); respErr.ErrCode != "" {
+			wrap = respErr
+		}
+		return gomatrix.HTTPError{
+			Contents:     contents,
+			Code:         res.StatusCode,
+			Message:      fmt.Sprintf("Failed to GET JSON from %s: %s", req.URL.Path, contents),
+			WrappedError: wrap,
+		}
+	}
+
+	return json.Unmarshal(contents, resBody)
+}
+
 type RespRoomID struct {
 	RoomID  string   `json:"room_id"`
 	Servers []string `json:"servers"`
 }
 
-func (c *Client) GetRoomID(roomAlias string) (*RespRoomID, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetRoomID(ctx context.Context, roomAlias string) (*RespRoomID, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"roomAlias": roomAlias,
 	}).Debug("Getting Room ID...")
 
 	u := c.matrix.BuildBaseURL("_matrix/client/v3/directory/room/", roomAlias)
 
 	var resp *RespRoomID
-	err := c.matrix.MakeRequest("GET", u, nil, &resp)
+	err := c.get(ctx, u, &resp)
 	if err != nil {
 		return nil, err
 	}
-	log.WithFields(log.Fields{
+	util.Logger(ctx).WithFields(log.Fields{
 		"roomID": resp.RoomID,
 	}).Debug("Got Room ID")
 	return resp, nil
 }
 
-func (c *Client) GetMessages(roomID, from, to string, dir rune, limit int, filter string) (*gomatrix.RespMessages, error) {
-	log.WithFields(log.Fields{
+func (c *Client) GetMessages(ctx context.Context, roomID, from, to string, dir rune, limit int, filter string) (*gomatrix.RespMessages, error) {
+	util.Logger(ctx).WithFields(log.Fields{
 		"roomID": roomID,
 		"from":   from,
 		"to":     to,
@@ -97,27 +139,27 @@ func (c *Client) GetMessages(roomID, from, to string, dir rune, limit int, filte
 	u.RawQuery = q.Encode()
 
 	var resp *gomatrix.RespMessages
-	err = c.matrix.MakeRequest("GET", u.String(), nil, &resp)
+	err = c.get(ctx, u.String(), &resp)
 	if err != nil {
 		return nil, err
 	}
 
-	log.WithFields(log.Fields{
+	util.Logger(ctx).WithFields(log.Fields{
 		"chunk": resp.Chunk,
 	}).Debug("Got Messages")
 
 	return resp, nil
 }
 
-func (c *Client) GetLatestMessagesBy(roomAlias, author string, limit int) ([]gomatrix.Event, error) {
-	roomID, err := c.GetRoomID(roomAlias)
+func (c *Client) GetLatestMessagesBy(ctx context.Context, roomAlias, author string, limit int) ([]gomatrix.Event, error) {
+	roomID, err := c.GetRoomID(ctx, roomAlias)
 	if err != nil {
 		return nil, err
 	}
 
 	filter := fmt.Sprintf(`{"types":["m.room.message"],"senders":["%s"]}`, author)
 
-	messages, err := c.GetMessages(roomID.RoomID, "", "", 'b', limit, filter)
+	messages, err := c.GetMessages(ctx, roomID.RoomID, "", "", 'b', limit, filter)
 	if err != nil {
 		return nil, err
 	}
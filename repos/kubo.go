@@ -50,5 +50,5 @@ func (k kubo) ReleaseIssueTitle(version *util.Version) string {
 }
 
 func (k kubo) ReleaseURL(version *util.Version) string {
-	return fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", k.Owner, k.Repo, version)
+	return util.GitHubURL("%s/%s/releases/tag/%s", k.Owner, k.Repo, version)
 }
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"io"
 	"os"
 	"os/exec"
@@ -8,12 +9,13 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// Stdout captures the output of a command while still showing it to the user
 type Stdout struct {
 	Writer io.Writer
 }
 
 func (s Stdout) Write(p []byte) (n int, err error) {
-	os.Stdout.Write(p)
+	Output.Write(p)
 	return s.Writer.Write(p)
 }
 
@@ -27,26 +29,35 @@ type Command struct {
 	Env    []string
 }
 
-func (c *Command) Run() error {
-	log.WithFields(log.Fields{
+func (c *Command) Run(ctx context.Context) error {
+	Logger(ctx).WithFields(log.Fields{
 		"name": c.Name,
 		"args": c.Args,
 		"dir":  c.Dir,
 	}).Debug("Running command...")
 
-	cmd := exec.Command(c.Name, c.Args...)
+	// the output shown to the user is prefixed with the name of the action the command runs for
+	stdout := Prefixed(ctx, Output)
+	defer stdout.Flush()
+	stderr := Prefixed(ctx, os.Stderr)
+	defer stderr.Flush()
+
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
 	if c.Dir != "" {
 		cmd.Dir = c.Dir
 	}
-	if c.Stdout != nil {
-		cmd.Stdout = c.Stdout
-	} else {
-		cmd.Stdout = os.Stdout
+	switch s := c.Stdout.(type) {
+	case nil:
+		cmd.Stdout = stdout
+	case Stdout:
+		cmd.Stdout = io.MultiWriter(s.Writer, stdout)
+	default:
+		cmd.Stdout = s
 	}
 	if c.Stderr != nil {
 		cmd.Stderr = c.Stderr
 	} else {
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = stderr
 	}
 	if c.Stdin != nil {
 		cmd.Stdin = c.Stdin
@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/term"
@@ -23,9 +24,9 @@ func GetenvPrompt(key string, prompt ...string) string {
 	value := Getenv(key, "")
 	if value == "" {
 		if len(prompt) > 0 {
-			fmt.Printf("🙋 %s is not set. %s: ", key, prompt[0])
+			fmt.Fprintf(Output, "🙋 %s is not set. %s: ", key, prompt[0])
 		} else {
-			fmt.Printf("🙋 %s is not set. Please enter a value: ", key)
+			fmt.Fprintf(Output, "🙋 %s is not set. Please enter a value: ", key)
 		}
 		fmt.Scanln(&value)
 	}
@@ -36,16 +37,25 @@ func GetenvPromptSecret(key string, prompt ...string) string {
 	value := Getenv(key, "")
 	if value == "" {
 		if len(prompt) > 0 {
-			fmt.Printf("🙋 %s is not set. %s: ", key, prompt[0])
+			fmt.Fprintf(Output, "🙋 %s is not set. %s: ", key, prompt[0])
 		} else {
-			fmt.Printf("🙋 %s is not set. Please enter a secret value: ", key)
+			fmt.Fprintf(Output, "🙋 %s is not set. Please enter a secret value: ", key)
 		}
 		bytes, err := term.ReadPassword(int(os.Stdin.Fd()))
 		if err != nil {
 			panic(err)
 		}
-		fmt.Println()
+		fmt.Fprintln(Output)
 		value = string(bytes)
 	}
 	return value
 }
+
+// CacheDir is where kuboreleaser keeps what it downloads, e.g. the workflow run logs (KUBORELEASER_CACHE_DIR)
+func CacheDir() string {
+	fallback := filepath.Join(".kuboreleaser", "cache")
+	if dir, err := os.UserCacheDir(); err == nil {
+		fallback = filepath.Join(dir, "kuboreleaser")
+	}
+	return Getenv("KUBORELEASER_CACHE_DIR", fallback)
+}
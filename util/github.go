@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The GitHub endpoints default to github.com. They are configured with the same environment variables that
+// GitHub Actions sets, so that kuboreleaser can be pointed at GitHub Enterprise Server or at a local stand-in.
+
+func isGitHubDotCom() bool {
+	return GitHubServerURL() == "https://github.com"
+}
+
+// GitHubServerURL is the base of the web URLs, e.g. https://github.com (GITHUB_SERVER_URL)
+func GitHubServerURL() string {
+	return strings.TrimSuffix(Getenv("GITHUB_SERVER_URL", "https://github.com"), "/")
+}
+
+// GitHubAPIURL is the base of the REST API, e.g. https://github.example.com/api/v3 on GHES (GITHUB_API_URL)
+func GitHubAPIURL() string {
+	fallback := GitHubServerURL() + "/api/v3"
+	if isGitHubDotCom() {
+		fallback = "https://api.github.com"
+	}
+	return strings.TrimSuffix(Getenv("GITHUB_API_URL", fallback), "/")
+}
+
+// GitHubUploadURL is the base of the release asset uploads (GITHUB_UPLOAD_URL)
+func GitHubUploadURL() string {
+	fallback := GitHubServerURL() + "/api/uploads"
+	if isGitHubDotCom() {
+		fallback = "https://uploads.github.com"
+	}
+	return strings.TrimSuffix(Getenv("GITHUB_UPLOAD_URL", fallback), "/")
+}
+
+// GitHubGraphQLURL is the GraphQL endpoint, e.g. https://github.example.com/api/graphql on GHES (GITHUB_GRAPHQL_URL)
+func GitHubGraphQLURL() string {
+	fallback := GitHubServerURL() + "/api/graphql"
+	if isGitHubDotCom() {
+		fallback = "https://api.github.com/graphql"
+	}
+	return Getenv("GITHUB_GRAPHQL_URL", fallback)
+}
+
+// GitHubGitURL is the base of the git remotes, it can also be a file:// URL of a directory with local remotes (GITHUB_GIT_URL)
+func GitHubGitURL() string {
+	return strings.TrimSuffix(Getenv("GITHUB_GIT_URL", GitHubServerURL()), "/")
+}
+
+// GitHubRemote returns the git remote of the repository
+func GitHubRemote(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", GitHubGitURL(), owner, repo)
+}
+
+// GitHubURL returns the web URL of the path, e.g. GitHubURL("%s/%s/pull/%d", owner, repo, number)
+func GitHubURL(format string, a ...interface{}) string {
+	return GitHubServerURL() + "/" + fmt.Sprintf(format, a...)
+}
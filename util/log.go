@@ -0,0 +1,80 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type actionKey struct{}
+
+// WithAction returns a context in which the messages logged with Logger and the output of the commands are attributed to the action
+func WithAction(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, actionKey{}, name)
+}
+
+// Logger returns the logger to use in the context, with the name of the action attached if there is one
+func Logger(ctx context.Context) *log.Entry {
+	if name, ok := ctx.Value(actionKey{}).(string); ok {
+		return log.WithField("action", name)
+	}
+	return log.NewEntry(log.StandardLogger())
+}
+
+// outputMu serializes the lines written by the commands of the actions running concurrently
+var outputMu sync.Mutex
+
+// PrefixWriter writes whole lines only, each prefixed with the name of the action, so that the output of concurrent commands does not interleave
+type PrefixWriter struct {
+	w      io.Writer
+	prefix []byte
+	line   []byte
+}
+
+// Prefixed returns a writer which prefixes the lines written to w with the name of the action of the context, if there is one.
+// It only writes whole lines, the last one has to be flushed.
+func Prefixed(ctx context.Context, w io.Writer) *PrefixWriter {
+	p := &PrefixWriter{w: w}
+	if name, ok := ctx.Value(actionKey{}).(string); ok {
+		p.prefix = []byte("[" + name + "] ")
+	}
+	return p
+}
+
+func (p *PrefixWriter) Write(b []byte) (int, error) {
+	p.line = append(p.line, b...)
+	i := bytes.LastIndexByte(p.line, '\n')
+	if i < 0 {
+		return len(b), nil
+	}
+	err := p.write(p.line[:i+1])
+	p.line = append(p.line[:0], p.line[i+1:]...)
+	return len(b), err
+}
+
+// Flush writes what is left of the last line
+func (p *PrefixWriter) Flush() error {
+	if len(p.line) == 0 {
+		return nil
+	}
+	err := p.write(append(p.line, '\n'))
+	p.line = p.line[:0]
+	return err
+}
+
+func (p *PrefixWriter) write(lines []byte) error {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			buf.Write(p.prefix)
+			buf.Write(line)
+		}
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	_, err := p.w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,10 @@
+package util
+
+import (
+	"io"
+	"os"
+)
+
+// Output is where the messages meant for the user, the prompts and the output of the commands go.
+// It is stdout unless stdout is reserved for the event stream, in which case it is stderr.
+var Output io.Writer = os.Stdout
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Operation struct {
+	Description string
+	Fields      log.Fields
+}
+
+// Plan collects the operations which would have been performed if we weren't in dry-run mode.
+type Plan struct {
+	mu         sync.Mutex
+	operations []Operation
+}
+
+func (p *Plan) Add(description string, fields log.Fields) {
+	log.WithFields(fields).Info("📋 Planned: ", description)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.operations = append(p.operations, Operation{
+		Description: description,
+		Fields:      fields,
+	})
+}
+
+func (p *Plan) Operations() []Operation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Operation(nil), p.operations...)
+}
+
+func (p *Plan) Print(w io.Writer) {
+	operations := p.Operations()
+	if len(operations) == 0 {
+		fmt.Fprintln(w, "📋 Dry run finished, nothing would have been changed")
+		return
+	}
+	fmt.Fprintln(w, "📋 Dry run finished, a real run would:")
+	for i, o := range operations {
+		fmt.Fprintf(w, "%d. %s\n", i+1, o.Description)
+	}
+}